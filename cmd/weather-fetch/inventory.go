@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/grib"
+)
+
+// inventory lists the messages of GRIB files: weather-fetch inventory [-json] <file>...
+func inventory(args []string) int {
+	flags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the inventory as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: weather-fetch inventory [-json] <file>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		entries, err := grib.Inventory(filename)
+
+		if *asJSON {
+			json.NewEncoder(os.Stdout).Encode(entries)
+		} else {
+			if flags.NArg() > 1 {
+				fmt.Println(filename)
+			}
+			printInventory(os.Stdout, entries)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err.Error())
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func printInventory(w io.Writer, entries []grib.InventoryEntry) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "OFFSET\tSHORTNAME\tNAME\tTYPEOFLEVEL\tLEVEL\tSTEP\tUNITS\tGRID\tMIN\tMAX\tMEAN\tMISSING")
+
+	for _, entry := range entries {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%d\t%s\t%s\t", entry.Offset, entry.ShortName, entry.Name, entry.TypeOfLevel, entry.Level, entry.StepRange, entry.Units)
+
+		if entry.Error != "" {
+			fmt.Fprintf(table, "error: %s\n", entry.Error)
+			continue
+		}
+		stats := entry.Stats
+		fmt.Fprintf(table, "%dx%d\t%.6g\t%.6g\t%.6g\t%d\n", entry.Nx, entry.Ny, stats.Min, stats.Max, stats.Mean, stats.Missing)
+	}
+
+	table.Flush()
+}
@@ -1,14 +1,38 @@
 package main
 
 import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/forecast"
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/server"
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/storage"
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
 )
 
 
 func main() {
-	storage.AnticipateExit()
-	go server.Serve()
-	forecast.StartFetching()
+	// weather-fetch inventory <file> lists what a GRIB file contains instead of fetching forecasts
+	if len(os.Args) > 1 && os.Args[1] == "inventory" {
+		os.Exit(inventory(os.Args[2:]))
+	}
+
+	utils.LoadEnv()
+
+	config, err := forecast.LoadConfig(os.Getenv("FORECAST_CONFIG"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go server.Serve(config)
+	forecast.StartFetching(ctx, forecast.NewSourceFromEnv(), config)
+
+	utils.Log("Cleaning up before exit...")
+	storage.CleanUpFiles("")
 }
\ No newline at end of file
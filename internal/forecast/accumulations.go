@@ -0,0 +1,149 @@
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// accumulations keeps the fields of the forecast groups with a window for every hour of a run. Hours are
+// decoded in any order, so the difference over a window is computed by whichever of its two hours comes last.
+type accumulations struct {
+	mu    sync.Mutex
+	hours map[int]map[string]*geometry.Grid
+}
+
+func newAccumulations() *accumulations {
+	return &accumulations{hours: make(map[int]map[string]*geometry.Grid)}
+}
+
+// windowStart is the hour a window ending at hour starts at, windows of the first hours of a run start with it
+func windowStart(hour int, window int) int {
+	return max(hour-window, 0)
+}
+
+// add stores the fields of an hour and returns, for each window, the hours of which the difference
+// over the window can be computed now that the fields of hour are known
+func (a *accumulations) add(hour int, grids map[string]*geometry.Grid, windows []int) map[int][]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hours[hour] = grids
+
+	ready := make(map[int][]int)
+	for _, window := range windows {
+		if _, exists := ready[window]; exists {
+			continue
+		}
+		ready[window] = []int{}
+
+		if a.known(windowStart(hour, window)) {
+			ready[window] = append(ready[window], hour)
+		}
+		if a.known(hour + window) {
+			ready[window] = append(ready[window], hour+window)
+		}
+	}
+
+	return ready
+}
+
+// known tells whether the fields of an hour were added, nothing is accumulated yet at the start of the run (hour 0)
+func (a *accumulations) known(hour int) bool {
+	_, exists := a.hours[hour]
+	return hour == 0 || exists
+}
+
+// difference returns the fields of hour minus their value at the start of the window
+func (a *accumulations) difference(hour int, window int) (map[string]*geometry.Grid, error) {
+	a.mu.Lock()
+	end, start := a.hours[hour], a.hours[windowStart(hour, window)]
+	a.mu.Unlock()
+
+	grids := make(map[string]*geometry.Grid, len(end))
+	for field, grid := range end {
+		difference, err := differenceGrid(grid, start[field])
+		if err != nil {
+			return nil, fmt.Errorf("field %q over %dh at hour %d: %w", field, window, hour, err)
+		}
+		grids[field] = difference
+	}
+
+	return grids, nil
+}
+
+// differenceGrid subtracts start from end point by point, start is nil at the start of the run.
+// Accumulations never decrease so negative differences are packing artefacts and become 0.
+func differenceGrid(end *geometry.Grid, start *geometry.Grid) (*geometry.Grid, error) {
+	if start != nil && start.GridDefinition != end.GridDefinition {
+		return nil, fmt.Errorf("the grid changed between the hours")
+	}
+
+	difference := &geometry.Grid{GridDefinition: end.GridDefinition, Values: make([]float32, len(end.Values))}
+	if end.Missing != nil || (start != nil && start.Missing != nil) {
+		difference.Missing = make([]bool, len(end.Values))
+	}
+
+	for i, value := range end.Values {
+		if end.IsMissing(i) || (start != nil && start.IsMissing(i)) {
+			difference.Values[i] = value
+			difference.Missing[i] = true
+			continue
+		}
+
+		if start != nil {
+			value -= start.Values[i]
+		}
+		difference.Values[i] = float32(math.Max(float64(value), 0))
+	}
+
+	return difference, nil
+}
+
+// processWindows stores the fields of an hour needed by the forecast groups with a window, and processes
+// those groups for every hour whose window is now complete. It returns these hours by forecast group name.
+func processWindows(grids map[string]*geometry.Grid, forecastPackage ForecastPackage, regions []Region, run string, hour string, history *accumulations) (map[string][]string, error) {
+	fields := make(map[string]*geometry.Grid)
+	windows := []int{}
+	for _, forecastGroup := range forecastPackage.Forecasts {
+		if forecastGroup.Window == 0 {
+			continue
+		}
+
+		windows = append(windows, forecastGroup.Window)
+		for _, field := range forecastGroup.Fields {
+			fields[field.Key()] = grids[field.Key()]
+		}
+	}
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	hourNumber, err := strconv.Atoi(hour)
+	if err != nil {
+		return nil, err
+	}
+
+	ready := history.add(hourNumber, fields, windows)
+
+	processed := make(map[string][]string)
+	for _, forecastGroup := range forecastPackage.Forecasts {
+		for _, end := range ready[forecastGroup.Window] {
+			differences, err := history.difference(end, forecastGroup.Window)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", forecastGroup.CommonName, err)
+			}
+
+			endHour := fmt.Sprintf("%02d", end)
+			if _, err := ProcessSingleForecast(differences, forecastGroup, regions, run, endHour); err != nil {
+				return nil, fmt.Errorf("%s: %w", forecastGroup.CommonName, err)
+			}
+			processed[forecastGroup.CommonName] = append(processed[forecastGroup.CommonName], endHour)
+		}
+	}
+
+	return processed, nil
+}
@@ -0,0 +1,64 @@
+package forecast
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+func TestAccumulationsComputeEveryWindowOnce(t *testing.T) {
+	history := newAccumulations()
+	definition := geometry.GridDefinition{Nx: 1, Ny: 1}
+
+	// Hours are decoded in any order
+	computed := map[int][]int{}
+	for _, hour := range []int{4, 2, 1, 5, 3} {
+		grids := map[string]*geometry.Grid{"tirf": {GridDefinition: definition, Values: []float32{float32(hour)}}}
+
+		for window, hours := range history.add(hour, grids, []int{1, 3}) {
+			computed[window] = append(computed[window], hours...)
+		}
+	}
+
+	for window, hours := range computed {
+		slices.Sort(hours)
+		if expected := []int{1, 2, 3, 4, 5}; !slices.Equal(hours, expected) {
+			t.Errorf("hours computed over %dh = %v; want %v", window, hours, expected)
+		}
+	}
+
+	// Windows of the first hours start with the run
+	for _, tc := range []struct {
+		hour, window int
+		expected     float32
+	}{{5, 1, 1}, {5, 3, 3}, {2, 3, 2}} {
+		grids, err := history.difference(tc.hour, tc.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := grids["tirf"].Values[0]; value != tc.expected {
+			t.Errorf("difference(%d, %d) = %v; want %v", tc.hour, tc.window, value, tc.expected)
+		}
+	}
+}
+
+func TestDifferenceGrid(t *testing.T) {
+	definition := geometry.GridDefinition{Nx: 3, Ny: 1}
+	start := &geometry.Grid{GridDefinition: definition, Values: []float32{1, 2.5, 9999}, Missing: []bool{false, false, true}}
+	end := &geometry.Grid{GridDefinition: definition, Values: []float32{4, 2.4, 3}}
+
+	difference, err := differenceGrid(end, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The accumulation can't decrease, 2.4 after 2.5 is an artefact of the packing
+	if difference.Values[0] != 3 || difference.Values[1] != 0 || !difference.IsMissing(2) {
+		t.Errorf("differenceGrid() = %v (missing %v); want [3 0 missing]", difference.Values, difference.Missing)
+	}
+
+	if _, err := differenceGrid(end, &geometry.Grid{GridDefinition: geometry.GridDefinition{Nx: 1, Ny: 3}, Values: []float32{0, 0, 0}}); err == nil {
+		t.Error("differenceGrid() on different grids returned no error")
+	}
+}
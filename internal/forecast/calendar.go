@@ -0,0 +1,97 @@
+package forecast
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+var DEFAULT_RUN_CYCLES = []int{0, 3, 6, 9, 12, 15, 18, 21}
+
+// RunCalendar computes the runs AROME should have published at a given time.
+// Everything is done in UTC, whatever the time zone of the server.
+type RunCalendar struct {
+	Clock Clock
+	// Cycles are the hours (UTC) at which a run starts
+	Cycles []int
+	// PublicationDelay is the time between the start of a run and the publication of its files
+	PublicationDelay time.Duration
+}
+
+// runCalendarFromEnv reads RUN_CYCLES (like "0,3,6,9,12,15,18,21") and RUN_PUBLICATION_DELAY (like "2h")
+func runCalendarFromEnv(clock Clock) RunCalendar {
+	cycles := DEFAULT_RUN_CYCLES
+	if value := os.Getenv("RUN_CYCLES"); value != "" {
+		parsed, err := parseRunCycles(value)
+		if err != nil {
+			utils.Log("Invalid RUN_CYCLES " + value + ", using the default ones: " + err.Error())
+		} else {
+			cycles = parsed
+		}
+	}
+
+	return RunCalendar{
+		Clock:            clock,
+		Cycles:           cycles,
+		PublicationDelay: utils.GetEnvDuration("RUN_PUBLICATION_DELAY", 0),
+	}
+}
+
+func parseRunCycles(value string) ([]int, error) {
+	cycles := []int{}
+	for _, part := range strings.Split(value, ",") {
+		cycle, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if cycle < 0 || cycle > 23 {
+			return nil, strconv.ErrRange
+		}
+		cycles = append(cycles, cycle)
+	}
+
+	return cycles, nil
+}
+
+// Runs returns the runs of the current and previous day that should be published by now,
+// most recent first, formatted like the run datetimes of data.gouv.fr (2025-06-19T06:00:00Z).
+func (c RunCalendar) Runs() []string {
+	published := c.Clock.Now().UTC().Add(-c.PublicationDelay)
+	today := time.Date(published.Year(), published.Month(), published.Day(), 0, 0, 0, 0, time.UTC)
+
+	cycles := append([]int{}, c.Cycles...)
+	sort.Sort(sort.Reverse(sort.IntSlice(cycles)))
+
+	runs := []string{}
+	// In early morning only runs from the previous day are available
+	for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+		for _, cycle := range cycles {
+			run := day.Add(time.Duration(cycle) * time.Hour)
+			if !run.After(published) {
+				runs = append(runs, run.Format("2006-01-02T15:04:05Z"))
+			}
+		}
+	}
+
+	return runs
+}
+
+// sortRunsNewestFirst sorts runs from the most recent to the oldest. Run datetimes are ISO 8601
+// in UTC, so their lexical order is the chronological one and runs are compared as strings.
+func sortRunsNewestFirst(runs []string) {
+	sort.Sort(sort.Reverse(sort.StringSlice(runs)))
+}
+
+// latestRun returns the most recent of some runs, or "" when there is none, see sortRunsNewestFirst
+func latestRun(runs []string) string {
+	latest := ""
+	for _, run := range runs {
+		latest = max(latest, run)
+	}
+
+	return latest
+}
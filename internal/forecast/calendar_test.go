@@ -0,0 +1,72 @@
+package forecast
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunCalendarRuns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		now      time.Time
+		cycles   []int
+		delay    time.Duration
+		expected []string
+	}{
+		{
+			name:   "Afternoon",
+			now:    time.Date(2025, 6, 19, 13, 10, 0, 0, time.UTC),
+			cycles: []int{0, 6, 12, 18},
+			expected: []string{
+				"2025-06-19T12:00:00Z", "2025-06-19T06:00:00Z", "2025-06-19T00:00:00Z",
+				"2025-06-18T18:00:00Z", "2025-06-18T12:00:00Z", "2025-06-18T06:00:00Z", "2025-06-18T00:00:00Z",
+			},
+		},
+		{
+			name:   "Just after midnight",
+			now:    time.Date(2025, 6, 19, 0, 5, 0, 0, time.UTC),
+			cycles: []int{0, 12},
+			expected: []string{
+				"2025-06-19T00:00:00Z", "2025-06-18T12:00:00Z", "2025-06-18T00:00:00Z",
+			},
+		},
+		{
+			name:   "Publication delay crossing midnight",
+			now:    time.Date(2025, 6, 19, 1, 0, 0, 0, time.UTC),
+			cycles: []int{0, 12},
+			delay:  2 * time.Hour,
+			expected: []string{
+				"2025-06-18T12:00:00Z", "2025-06-18T00:00:00Z", "2025-06-17T12:00:00Z", "2025-06-17T00:00:00Z",
+			},
+		},
+		{
+			name: "Server in Madrid summer time, already the next day locally",
+			// 01:30 in Madrid is still 23:30 the day before in UTC
+			now:    time.Date(2025, 6, 20, 1, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			cycles: []int{0, 12},
+			expected: []string{
+				"2025-06-19T12:00:00Z", "2025-06-19T00:00:00Z", "2025-06-18T12:00:00Z", "2025-06-18T00:00:00Z",
+			},
+		},
+		{
+			name: "Night of the switch to winter time",
+			// 02:30 CET on 2025-10-26, an hour after clocks went back, is 01:30 UTC
+			now:    time.Date(2025, 10, 26, 2, 30, 0, 0, time.FixedZone("CET", 60*60)),
+			cycles: []int{0, 3},
+			expected: []string{
+				"2025-10-26T00:00:00Z", "2025-10-25T03:00:00Z", "2025-10-25T00:00:00Z",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calendar := RunCalendar{Clock: &fakeClock{now: tc.now}, Cycles: tc.cycles, PublicationDelay: tc.delay}
+			runs := calendar.Runs()
+			if !slices.Equal(runs, tc.expected) {
+				t.Errorf("Runs() = %v; want %v", runs, tc.expected)
+			}
+		})
+	}
+}
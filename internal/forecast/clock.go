@@ -0,0 +1,19 @@
+package forecast
+
+import "time"
+
+// Clock gives the current time and waits, it is injected so that scheduling can be tested
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
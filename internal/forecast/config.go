@@ -0,0 +1,328 @@
+package forecast
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/forecast/fieldshandler"
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/grib"
+)
+
+// defaultConfig is used when no FORECAST_CONFIG file is given, it is also a good starting point for one
+//
+//go:embed config.json
+var defaultConfig []byte
+
+// Config declares what is fetched from each package, how it is processed and for which regions
+type Config struct {
+	Regions []Region `json:"regions"`
+	// DefaultRegion is served without the region in the URL, it is the first region when empty
+	DefaultRegion string            `json:"default_region"`
+	Packages      []ForecastPackage `json:"packages"`
+}
+
+// Region is the area forecasts are served for, points outside of it are dropped.
+// Its boundaries are either a single polygon or a GeoJSON file.
+type Region struct {
+	Name    string           `json:"name"`
+	Polygon []geometry.Point `json:"polygon"`
+	// GeoJSON is the path of a file with the boundaries of the region, relative to the configuration file
+	GeoJSON string `json:"geojson"`
+	// Area is built from Polygon or GeoJSON when the configuration is loaded
+	Area geometry.MultiPolygon `json:"-"`
+	// Mask remembers which grid points are inside Area, it is shared by every field and hour
+	Mask *geometry.MaskedArea `json:"-"`
+}
+
+// Names are used in file names and URLs
+var namePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// reservedNames are served by the API itself at /{name}.json, a forecast can't take them
+var reservedNames = map[string]bool{"runs": true, "status": true}
+
+// LoadConfig reads the JSON configuration at path, or the default one when path is empty
+func LoadConfig(path string) (*Config, error) {
+	content := defaultConfig
+	source := "default configuration"
+	dir := "."
+
+	if path != "" {
+		var err error
+		content, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		source = path
+		dir = filepath.Dir(path)
+	}
+
+	config, err := parseConfig(content, dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", source, err)
+	}
+
+	return config, nil
+}
+
+// parseConfig decodes and validates a configuration, GeoJSON files are looked for in dir
+func parseConfig(content []byte, dir string) (*Config, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// Report typos like "feilds" instead of silently ignoring them
+	decoder.DisallowUnknownFields()
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, describeJSONError(content, err)
+	}
+
+	if err := config.validate(dir); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// describeJSONError adds the line and column of syntax and type errors, which encoding/json only gives as an offset
+func describeJSONError(content []byte, err error) error {
+	var offset int64
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		offset = syntaxError.Offset
+	case errors.As(err, &typeError):
+		offset = typeError.Offset
+	default:
+		return err
+	}
+
+	// The offset is right after the last byte read, which is the one in error
+	offset = max(offset-1, 0)
+	line := 1 + bytes.Count(content[:offset], []byte("\n"))
+	column := int(offset) - bytes.LastIndex(content[:offset], []byte("\n"))
+
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+func (c *Config) validate(dir string) error {
+	var errs []error
+	addError := func(path string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if len(c.Regions) == 0 {
+		addError("regions", "at least one region is required")
+	}
+
+	regionNames := make([]string, 0, len(c.Regions))
+	for i := range c.Regions {
+		region := &c.Regions[i]
+		path := fmt.Sprintf("regions[%d]", i)
+
+		if !namePattern.MatchString(region.Name) {
+			addError(path+".name", "%q must only contain lowercase letters, digits and underscores", region.Name)
+		} else if slices.Contains(regionNames, region.Name) {
+			addError(path+".name", "%q is declared twice", region.Name)
+		}
+		regionNames = append(regionNames, region.Name)
+
+		switch {
+		case region.GeoJSON != "" && len(region.Polygon) > 0:
+			addError(path, "polygon and geojson can't be both set")
+
+		case region.GeoJSON != "":
+			geoJSONPath := region.GeoJSON
+			if !filepath.IsAbs(geoJSONPath) {
+				geoJSONPath = filepath.Join(dir, geoJSONPath)
+			}
+
+			area, err := geometry.LoadGeoJSON(geoJSONPath)
+			if err != nil {
+				addError(path+".geojson", "%s", err.Error())
+			}
+			region.Area = area
+
+		default:
+			if len(region.Polygon) < 3 {
+				addError(path+".polygon", "needs at least 3 points, got %d", len(region.Polygon))
+			}
+			region.Area = geometry.MultiPolygon{{region.Polygon}}
+		}
+
+		if point, valid := firstInvalidPoint(region.Area); !valid {
+			addError(path, "%v is not a valid lat/lon", point)
+		}
+		region.Mask = geometry.NewMaskedArea(region.Area)
+	}
+
+	if c.DefaultRegion == "" && len(c.Regions) > 0 {
+		c.DefaultRegion = c.Regions[0].Name
+	} else if c.DefaultRegion != "" && !slices.Contains(regionNames, c.DefaultRegion) {
+		addError("default_region", "unknown region %q%s", c.DefaultRegion, suggest(c.DefaultRegion, regionNames))
+	}
+
+	if len(c.Packages) == 0 {
+		addError("packages", "at least one package is required")
+	}
+
+	packageNames := make(map[string]bool)
+	forecastNames := make(map[string]bool)
+	for i := range c.Packages {
+		forecastPackage := &c.Packages[i]
+		path := fmt.Sprintf("packages[%d]", i)
+
+		if forecastPackage.Package == "" {
+			addError(path+".package", "is required (e.g. SP1)")
+		} else if packageNames[forecastPackage.Package] {
+			addError(path+".package", "%q is declared twice", forecastPackage.Package)
+		}
+		packageNames[forecastPackage.Package] = true
+
+		if len(forecastPackage.Forecasts) == 0 {
+			addError(path+".forecasts", "at least one forecast is required")
+		}
+
+		// Fields of a package are decoded once for all its forecasts, so a key must always select the same messages
+		selectors := make(map[string]grib.Selector)
+
+		for j := range forecastPackage.Forecasts {
+			forecastGroup := &forecastPackage.Forecasts[j]
+			path := fmt.Sprintf("%s.forecasts[%d]", path, j)
+
+			if !namePattern.MatchString(forecastGroup.CommonName) {
+				addError(path+".name", "%q must only contain lowercase letters, digits and underscores", forecastGroup.CommonName)
+			} else if reservedNames[forecastGroup.CommonName] {
+				addError(path+".name", "%q is reserved by the API", forecastGroup.CommonName)
+			} else if forecastNames[forecastGroup.CommonName] {
+				// Forecasts are served at /{name}.json so names must be unique across packages
+				addError(path+".name", "%q is declared twice", forecastGroup.CommonName)
+			}
+			forecastNames[forecastGroup.CommonName] = true
+
+			if len(forecastGroup.Fields) == 0 {
+				addError(path+".fields", "at least one GRIB field is required")
+			}
+			for k, field := range forecastGroup.Fields {
+				path := fmt.Sprintf("%s.fields[%d]", path, k)
+
+				if strings.TrimSpace(field.ShortName) == "" {
+					addError(path, "shortName is required")
+					continue
+				}
+
+				if selector, exists := selectors[field.Key()]; exists && !selector.Equal(field) {
+					addError(path, "%q is %s here but %s in another forecast of the package, use \"as\" to give them different names", field.Key(), field, selector)
+				}
+				selectors[field.Key()] = field
+
+				for l, other := range forecastGroup.Fields[:k] {
+					if field.Key() == other.Key() {
+						addError(path, "%q is declared twice, use \"as\" to tell fields with the same shortName apart", field.Key())
+					} else if field.Overlaps(other) {
+						// Both would get the same messages
+						addError(path, "%s is ambiguous with fields[%d] (%s), set typeOfLevel, level or stepType", field, l, other)
+					}
+				}
+			}
+
+			if forecastGroup.Window < 0 || forecastGroup.Window > FORECAST_HOURS {
+				addError(path+".window", "must be between 1 and %d hours, or 0 for no window", FORECAST_HOURS)
+			} else if forecastGroup.Window > 0 && forecastGroup.Optional {
+				// A window needs the fields of every hour, they can't come and go
+				addError(path+".optional", "a group with a window can't be optional")
+			}
+
+			if forecastGroup.Handler == "" {
+				forecastGroup.Handler = "default"
+			}
+			handler, exists := fieldshandler.Lookup(forecastGroup.Handler)
+			if !exists {
+				addError(path+".handler", "unknown handler %q%s", forecastGroup.Handler, suggest(forecastGroup.Handler, fieldshandler.Names()))
+				continue
+			}
+			for _, input := range handler.Inputs() {
+				if !slices.ContainsFunc(forecastGroup.Fields, func(field grib.Selector) bool { return field.Key() == input }) {
+					addError(path+".fields", "handler %q needs a field named %q", forecastGroup.Handler, input)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// firstInvalidPoint returns the first point out of the lat/lon range, e.g. when a GeoJSON file isn't in WGS 84
+func firstInvalidPoint(area geometry.MultiPolygon) (geometry.Point, bool) {
+	for _, polygon := range area {
+		for _, ring := range polygon {
+			for _, point := range ring {
+				if point.Lat < -90 || point.Lat > 90 || point.Lon < -180 || point.Lon > 180 {
+					return point, false
+				}
+			}
+		}
+	}
+
+	return geometry.Point{}, true
+}
+
+// hasPackage tells whether a package like SP1 is fetched
+func (c *Config) hasPackage(packageName string) bool {
+	for _, forecastPackage := range c.Packages {
+		if forecastPackage.Package == packageName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suggest returns a hint with the closest valid name when value looks like a typo, and the list of valid names otherwise
+func suggest(value string, names []string) string {
+	closest := ""
+	closestDistance := 3 // More edits than that is not a typo
+	for _, name := range names {
+		if distance := levenshtein(value, name); distance < closestDistance {
+			closest, closestDistance = name, distance
+		}
+	}
+
+	if closest != "" {
+		return fmt.Sprintf(", did you mean %q?", closest)
+	}
+
+	return fmt.Sprintf(", expected one of %s", strings.Join(names, ", "))
+}
+
+// levenshtein is the number of single character edits needed to turn a into b
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,149 @@
+package forecast
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDefaultConfig(t *testing.T) {
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(config.Packages) != 2 || config.DefaultRegion != "comunidad_valenciana" {
+		t.Errorf("default configuration has %d packages and default region %q; want 2 packages and comunidad_valenciana", len(config.Packages), config.DefaultRegion)
+	}
+}
+
+func TestLoadConfigWithGeoJSONRegion(t *testing.T) {
+	dir := t.TempDir()
+	geoJSON := `{"type": "Polygon", "coordinates": [[[0, 38], [2, 38], [2, 40], [0, 40], [0, 38]], [[0.5, 38.5], [1.5, 38.5], [1.5, 39.5], [0.5, 38.5]]]}`
+	config := `{"regions": [{"name": "valencia", "geojson": "valencia.geojson"}], "packages": [{"package": "SP1", "forecasts": [{"name": "temperature", "fields": ["t2m"]}]}]}`
+
+	if err := os.WriteFile(filepath.Join(dir, "valencia.geojson"), []byte(geoJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	area := loaded.Regions[0].Area
+	if len(area) != 1 || len(area[0]) != 2 {
+		t.Errorf("area of the region = %v; want a polygon with a hole", area)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	const region = `"regions": [{"name": "valencia", "polygon": [{"lat": 39, "lon": 0}, {"lat": 40, "lon": 0}, {"lat": 40, "lon": 1}]}]`
+
+	testCases := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name:     "Typo in a key",
+			config:   `{` + region + `, "packages": [{"package": "SP1", "forecasts": [{"name": "temperature", "feilds": ["t2m"]}]}]}`,
+			expected: []string{`unknown field "feilds"`},
+		},
+		{
+			name:     "Typo in a handler",
+			config:   `{` + region + `, "packages": [{"package": "SP1", "forecasts": [{"name": "comfort", "fields": ["t2m"], "handler": "confort_index"}]}]}`,
+			expected: []string{`packages[0].forecasts[0].handler: unknown handler "confort_index", did you mean "comfort_index"?`},
+		},
+		{
+			name:     "Field missing for a handler",
+			config:   `{` + region + `, "packages": [{"package": "SP1", "forecasts": [{"name": "comfort", "fields": ["t2m", "u10", "v10", "r"], "handler": "comfort_index"}]}]}`,
+			expected: []string{`packages[0].forecasts[0].fields: handler "comfort_index" needs a field named "r2"`},
+		},
+		{
+			name:     "Reserved name",
+			config:   `{` + region + `, "packages": [{"package": "SP1", "forecasts": [{"name": "status", "fields": ["t2m"]}]}]}`,
+			expected: []string{`packages[0].forecasts[0].name: "status" is reserved by the API`},
+		},
+		{
+			name:     "Optional group with a window",
+			config:   `{` + region + `, "packages": [{"package": "SP2", "forecasts": [{"name": "rainfall_3h", "fields": ["tirf"], "window": 3, "optional": true}]}]}`,
+			expected: []string{`packages[0].forecasts[0].optional: a group with a window can't be optional`},
+		},
+		{
+			name:   "Syntax error",
+			config: "{\n  " + region + ",\n  \"packages\": [}\n}",
+			expected: []string{
+				"line 3, column 16",
+			},
+		},
+		{
+			name:     "Same key selecting different fields in a package",
+			config:   `{` + region + `, "packages": [{"package": "SP1", "forecasts": [{"name": "temperature", "fields": [{"shortName": "2t", "as": "t"}]}, {"name": "dew_point", "fields": [{"shortName": "2d", "as": "t"}]}]}]}`,
+			expected: []string{`packages[0].forecasts[1].fields[0]: "t" is shortName=2d here but shortName=2t in another forecast of the package`},
+		},
+		{
+			name:   "Missing and duplicated values",
+			config: `{"regions": [{"name": "Valencia", "polygon": []}], "default_region": "murcia", "packages": [{"package": "SP1", "forecasts": [{"name": "temperature", "fields": []}, {"name": "temperature", "fields": ["t2m"]}]}]}`,
+			expected: []string{
+				`regions[0].name: "Valencia" must only contain lowercase letters, digits and underscores`,
+				"regions[0].polygon: needs at least 3 points, got 0",
+				`default_region: unknown region "murcia"`,
+				"packages[0].forecasts[0].fields: at least one GRIB field is required",
+				`packages[0].forecasts[1].name: "temperature" is declared twice`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseConfig([]byte(tc.config), ".")
+			if err == nil {
+				t.Fatal("parseConfig() returned no error")
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("parseConfig() = %q; want it to contain %q", err.Error(), expected)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfigFieldSelectors(t *testing.T) {
+	const region = `"regions": [{"name": "valencia", "polygon": [{"lat": 39, "lon": 0}, {"lat": 40, "lon": 0}, {"lat": 40, "lon": 1}]}]`
+
+	testCases := []struct {
+		name     string
+		fields   string
+		expected string
+	}{
+		{name: "Short names and selectors", fields: `["lcc", {"shortName": "2t", "typeOfLevel": "heightAboveGround", "level": 2, "as": "t2m"}]`},
+		{name: "Same field at two levels", fields: `[{"shortName": "u", "level": 10, "as": "u10"}, {"shortName": "u", "level": 100, "as": "u100"}]`},
+		{name: "Missing shortName", fields: `[{"typeOfLevel": "surface"}]`, expected: "packages[0].forecasts[0].fields[0]: shortName is required"},
+		{name: "Same key twice", fields: `["lcc", "lcc"]`, expected: `packages[0].forecasts[0].fields[1]: "lcc" is declared twice`},
+		{name: "Ambiguous selectors", fields: `[{"shortName": "u", "as": "u"}, {"shortName": "u", "level": 10, "as": "u10"}]`, expected: "fields[1]: shortName=u level=10 is ambiguous with fields[0]"},
+		{name: "Typo in a selector", fields: `[{"shortName": "2t", "typeOfLvel": "surface"}]`, expected: `unknown field "typeOfLvel"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := `{` + region + `, "packages": [{"package": "SP1", "forecasts": [{"name": "test", "fields": ` + tc.fields + `}]}]}`
+
+			_, err := parseConfig([]byte(config), ".")
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("parseConfig() = %q; want no error", err.Error())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("parseConfig() = %v; want it to contain %q", err, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package forecast
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+// runCheck is what is known about a run of a package after probing it
+type runCheck struct {
+	complete  bool
+	checkedAt time.Time
+	// missingHour is the first hour found missing, it is probed first on the next check
+	missingHour string
+}
+
+// RunDiscovery finds the latest complete run of a package while keeping requests to the source low:
+// complete runs are remembered, incomplete ones are only probed again after RecheckAfter, and the
+// last hour (published last) is probed before fanning out to all the others.
+type RunDiscovery struct {
+	Source Source
+	Clock  Clock
+	// RecheckAfter is how long a run known to be incomplete is not probed again
+	RecheckAfter time.Duration
+	// Concurrency is the number of requests in flight when checking all the hours of a run
+	Concurrency int
+	// RequestsPerSecond throttles requests to the source, 0 means no limit
+	RequestsPerSecond float64
+
+	mu          sync.Mutex
+	runs        map[string]map[string]runCheck
+	nextRequest time.Time
+}
+
+func newRunDiscoveryFromEnv(source Source, clock Clock) *RunDiscovery {
+	return &RunDiscovery{
+		Source:            source,
+		Clock:             clock,
+		RecheckAfter:      utils.GetEnvDuration("DISCOVERY_RECHECK_INTERVAL", 2*time.Minute),
+		Concurrency:       utils.GetEnvInt("DISCOVERY_CONCURRENCY", 8),
+		RequestsPerSecond: utils.GetEnvFloat("DISCOVERY_REQUESTS_PER_SECOND", 20),
+	}
+}
+
+// LatestCompleteRun returns the most recent run of a package whose hours are all available, or "" if none is
+func (d *RunDiscovery) LatestCompleteRun(ctx context.Context, packageName string) string {
+	runs, err := d.Source.ListRuns(ctx, packageName)
+	if err != nil {
+		utils.Log("Error listing runs for package " + packageName + ": " + err.Error())
+		return ""
+	}
+
+	d.forgetOtherRuns(packageName, runs)
+
+	for _, run := range runs {
+		check, known := d.check(packageName, run)
+		if known && check.complete {
+			return run
+		}
+		if known && d.Clock.Now().Sub(check.checkedAt) < d.RecheckAfter {
+			continue
+		}
+
+		check = d.probe(ctx, packageName, run, check.missingHour)
+		if ctx.Err() != nil {
+			return ""
+		}
+		d.remember(packageName, run, check)
+
+		if check.complete {
+			return run
+		}
+	}
+
+	return ""
+}
+
+// probe checks the hours of a run, starting with the one that was missing last time, then the last one
+func (d *RunDiscovery) probe(ctx context.Context, packageName string, run string, missingHour string) runCheck {
+	hours := getAvailableHours()
+	lastHour := hours[len(hours)-1]
+
+	firstHours := []string{lastHour}
+	if missingHour != "" && missingHour != lastHour {
+		firstHours = []string{missingHour, lastHour}
+	}
+
+	for _, hour := range firstHours {
+		if !d.isAvailable(ctx, packageName, run, hour) {
+			return runCheck{checkedAt: d.Clock.Now(), missingHour: hour}
+		}
+	}
+
+	remainingHours := []string{}
+	for _, hour := range hours {
+		if hour != lastHour && hour != missingHour {
+			remainingHours = append(remainingHours, hour)
+		}
+	}
+
+	missing := d.firstMissingHour(ctx, packageName, run, remainingHours)
+
+	return runCheck{complete: missing == "", checkedAt: d.Clock.Now(), missingHour: missing}
+}
+
+// firstMissingHour checks hours in parallel and returns the earliest one that isn't available, or ""
+func (d *RunDiscovery) firstMissingHour(ctx context.Context, packageName string, run string, hours []string) string {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	available := make([]bool, len(hours))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < max(d.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				available[index] = d.isAvailable(ctx, packageName, run, hours[index])
+				if !available[index] {
+					// No need to check the other hours, the run is incomplete anyway
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range hours {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, hour := range hours {
+		if !available[i] {
+			return hour
+		}
+	}
+
+	return ""
+}
+
+func (d *RunDiscovery) isAvailable(ctx context.Context, packageName string, run string, hour string) bool {
+	if !d.throttle(ctx) {
+		return false
+	}
+
+	return d.Source.IsAvailable(ctx, packageName, run, hour)
+}
+
+// throttle waits until the next request is allowed by RequestsPerSecond, it returns false if ctx is cancelled first
+func (d *RunDiscovery) throttle(ctx context.Context) bool {
+	if d.RequestsPerSecond <= 0 {
+		return ctx.Err() == nil
+	}
+
+	d.mu.Lock()
+	now := d.Clock.Now()
+	wait := d.nextRequest.Sub(now)
+	d.nextRequest = now.Add(max(wait, 0) + time.Duration(float64(time.Second)/d.RequestsPerSecond))
+	d.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-d.Clock.After(wait):
+		return true
+	}
+}
+
+func (d *RunDiscovery) check(packageName string, run string) (runCheck, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	check, known := d.runs[packageName][run]
+	return check, known
+}
+
+func (d *RunDiscovery) remember(packageName string, run string, check runCheck) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.runs == nil {
+		d.runs = make(map[string]map[string]runCheck)
+	}
+	if d.runs[packageName] == nil {
+		d.runs[packageName] = make(map[string]runCheck)
+	}
+	d.runs[packageName][run] = check
+}
+
+// forgetOtherRuns drops runs that are not candidates anymore so that the cache doesn't grow forever
+func (d *RunDiscovery) forgetOtherRuns(packageName string, runs []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	candidates := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		candidates[run] = true
+	}
+
+	for run := range d.runs[packageName] {
+		if !candidates[run] {
+			delete(d.runs[packageName], run)
+		}
+	}
+}
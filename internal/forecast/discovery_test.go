@@ -0,0 +1,93 @@
+package forecast
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingSource publishes the given number of hours of every run and counts availability checks
+type countingSource struct {
+	mu             sync.Mutex
+	runs           []string
+	publishedHours map[string]int
+	checks         int
+}
+
+func (s *countingSource) ListRuns(ctx context.Context, packageName string) ([]string, error) {
+	return s.runs, nil
+}
+
+func (s *countingSource) IsAvailable(ctx context.Context, packageName string, run string, hour string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checks++
+	return hour <= getAvailableHours()[s.publishedHours[run]-1]
+}
+
+func (s *countingSource) Open(ctx context.Context, packageName string, run string, hour string, offset int64) (io.ReadCloser, int64, error) {
+	return nil, -1, io.EOF
+}
+
+func (s *countingSource) resetChecks() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checks := s.checks
+	s.checks = 0
+	return checks
+}
+
+func TestRunDiscovery(t *testing.T) {
+	source := &countingSource{
+		runs: []string{"2025-06-19T09:00:00Z", "2025-06-19T06:00:00Z"},
+		publishedHours: map[string]int{
+			"2025-06-19T09:00:00Z": 20,
+			"2025-06-19T06:00:00Z": FORECAST_HOURS,
+		},
+	}
+	clock := &fakeClock{now: time.Date(2025, 6, 19, 11, 0, 0, 0, time.UTC)}
+	discovery := &RunDiscovery{Source: source, Clock: clock, RecheckAfter: 5 * time.Minute, Concurrency: 4}
+
+	ctx := context.Background()
+
+	if run := discovery.LatestCompleteRun(ctx, "SP1"); run != "2025-06-19T06:00:00Z" {
+		t.Errorf("LatestCompleteRun() = %q; want 2025-06-19T06:00:00Z", run)
+	}
+	// One probe of the last hour of the incomplete run, then every hour of the complete one
+	if checks := source.resetChecks(); checks != 1+FORECAST_HOURS {
+		t.Errorf("first discovery made %d checks; want %d", checks, 1+FORECAST_HOURS)
+	}
+
+	// Both runs are remembered
+	discovery.LatestCompleteRun(ctx, "SP1")
+	if checks := source.resetChecks(); checks != 0 {
+		t.Errorf("second discovery made %d checks; want 0", checks)
+	}
+
+	// Once RecheckAfter is elapsed, the incomplete run is probed again with a single request
+	clock.now = clock.now.Add(6 * time.Minute)
+	discovery.LatestCompleteRun(ctx, "SP1")
+	if checks := source.resetChecks(); checks != 1 {
+		t.Errorf("discovery after RecheckAfter made %d checks; want 1", checks)
+	}
+
+	// The newest run eventually gets complete
+	source.publishedHours["2025-06-19T09:00:00Z"] = FORECAST_HOURS
+	clock.now = clock.now.Add(6 * time.Minute)
+	if run := discovery.LatestCompleteRun(ctx, "SP1"); run != "2025-06-19T09:00:00Z" {
+		t.Errorf("LatestCompleteRun() = %q; want 2025-06-19T09:00:00Z", run)
+	}
+}
+
+func TestRunDiscoveryRateBelowOnePerSecond(t *testing.T) {
+	t.Setenv("DISCOVERY_REQUESTS_PER_SECOND", "0.5")
+
+	discovery := newRunDiscoveryFromEnv(&DirSource{Dir: t.TempDir()}, systemClock{})
+	if discovery.RequestsPerSecond != 0.5 {
+		t.Errorf("RequestsPerSecond = %v; want 0.5", discovery.RequestsPerSecond)
+	}
+}
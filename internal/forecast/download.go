@@ -0,0 +1,125 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+const (
+	DOWNLOAD_RESUME_ATTEMPTS = 5
+	// A download receiving nothing for this long is interrupted, see DOWNLOAD_IDLE_TIMEOUT
+	DOWNLOAD_IDLE_TIMEOUT = time.Minute
+)
+
+// downloadPackage streams a GRIB file straight to ./tmp instead of holding it in memory.
+// The file is written under a .part name and only renamed once complete, so a file
+// without the suffix is always whole. When the connection drops after some bytes were
+// received, the download resumes from where it stopped. A file downloaded whole by a previous
+// check of the run is used as is.
+func downloadPackage(ctx context.Context, source Source, packageName string, dt string, hour string) (string, error) {
+	grib2file := downloadedFile(packageName, dt, hour)
+	partFile := grib2file + ".part"
+
+	if _, err := os.Stat(grib2file); err == nil {
+		return grib2file, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		written, err := resumeDownload(ctx, source, packageName, dt, hour, partFile)
+		if err == nil {
+			break
+		}
+
+		// Only resume when the last attempt made progress, other errors (like a 404) won't go away by themselves
+		if written == 0 || attempt >= DOWNLOAD_RESUME_ATTEMPTS || ctx.Err() != nil {
+			return "", err
+		}
+
+		utils.Log(fmt.Sprintf("Download of %s %s %s interrupted, resuming: %s", packageName, dt, hour, err.Error()))
+	}
+
+	if err := os.Rename(partFile, grib2file); err != nil {
+		return "", err
+	}
+
+	return grib2file, nil
+}
+
+// downloadedFile is where the GRIB file of an hour is kept until its run is rolled out
+func downloadedFile(packageName string, dt string, hour string) string {
+	return fmt.Sprintf("./tmp/file_%s_%s_%s.grib2", packageName, dt, hour)
+}
+
+// resumeDownload appends the missing bytes of a GRIB file to partFile and returns how many were written
+func resumeDownload(ctx context.Context, source Source, packageName string, dt string, hour string, partFile string) (int64, error) {
+	file, err := os.OpenFile(partFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	offset := info.Size()
+
+	// A stalled connection gives no error by itself and would hold its download slot forever,
+	// so the request is cancelled when no byte comes in for a while. It then resumes like a dropped one.
+	timeout := utils.GetEnvDuration("DOWNLOAD_IDLE_TIMEOUT", DOWNLOAD_IDLE_TIMEOUT)
+	downloadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(timeout, cancel)
+	defer idle.Stop()
+
+	reader, total, err := source.Open(downloadCtx, packageName, dt, hour, offset)
+	if err != nil {
+		return 0, idleError(ctx, downloadCtx, err)
+	}
+	defer reader.Close()
+
+	if total >= 0 && offset > total {
+		// The partial file does not belong to this file, start over
+		os.Remove(partFile)
+		return 0, fmt.Errorf("partial download of %d bytes is larger than the %d bytes file", offset, total)
+	}
+
+	written, err := io.Copy(file, &idleReader{reader: reader, idle: idle, timeout: timeout})
+	if err != nil {
+		return written, idleError(ctx, downloadCtx, err)
+	}
+
+	if total >= 0 && offset+written != total {
+		return written, fmt.Errorf("incomplete download: got %d of %d bytes", offset+written, total)
+	}
+
+	return written, file.Sync()
+}
+
+// idleReader pushes back the idle timer of a download every time some bytes are read
+type idleReader struct {
+	reader  io.Reader
+	idle    *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.idle.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// idleError tells apart a download cancelled for being idle from other errors, including a shutdown
+func idleError(ctx context.Context, downloadCtx context.Context, err error) error {
+	if ctx.Err() == nil && downloadCtx.Err() != nil {
+		return fmt.Errorf("no data received for too long: %w", err)
+	}
+	return err
+}
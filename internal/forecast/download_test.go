@@ -0,0 +1,97 @@
+package forecast
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDownloadPackageResumesPartialFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	content := bytes.Repeat([]byte("GRIB"), 1000)
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file.grib2", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	source := &HTTPSource{BaseURL: server.URL, Client: server.Client()}
+
+	// Simulate a previous download that dropped after 1500 bytes
+	partFile := "./tmp/file_SP1_2025-06-19T06:00:00Z_01.grib2.part"
+	if err := os.WriteFile(partFile, content[:1500], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	filename, err := downloadPackage(context.Background(), source, "SP1", "2025-06-19T06:00:00Z", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloaded, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("downloaded %d bytes; want the %d bytes of the original file", len(downloaded), len(content))
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=1500-" {
+		t.Errorf("requested ranges %v; want [bytes=1500-]", ranges)
+	}
+	if _, err := os.Stat(partFile); !os.IsNotExist(err) {
+		t.Errorf("partial file %s should have been renamed", partFile)
+	}
+}
+
+func TestDownloadPackageResumesStalledDownload(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOWNLOAD_IDLE_TIMEOUT", "100ms")
+
+	content := bytes.Repeat([]byte("GRIB"), 1000)
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) > 1 {
+			http.ServeContent(w, r, "file.grib2", time.Time{}, bytes.NewReader(content))
+			return
+		}
+
+		// Send the first bytes then hang without closing the connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:1500])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	source := &HTTPSource{BaseURL: server.URL, Client: server.Client()}
+
+	filename, err := downloadPackage(context.Background(), source, "SP1", "2025-06-19T06:00:00Z", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloaded, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("downloaded %d bytes; want the %d bytes of the original file", len(downloaded), len(content))
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=1500-" {
+		t.Errorf("requested ranges %v; want [ bytes=1500-]", ranges)
+	}
+}
@@ -1,10 +1,7 @@
 package fieldshandler
 
 import (
-	"fmt"
 	"math"
-
-	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
 )
 
 // cloudCover calculates total cloud cover from low, medium, and high cloud cover fractions.
@@ -26,57 +23,33 @@ func cloudCover(lcc, mcc, hcc float64) float64 {
 	return tccPercentage
 }
 
-// ProcessCloudCover aggregates cloud cover data (lcc, mcc, hcc) for geographic points,
-// calculates the total cloud cover for each point, and returns a map of points with their calculated cloud cover values.
-func ProcessCloudCover(pointsByField map[string][]geometry.GeoPoint) map[string]geometry.GeoPoint {
-	cloudDataMap := make(map[string]map[string]float64)
-	coordinateMap := make(map[string]geometry.GeoPoint)
-
-	// Collect cloud data by coordinate
-	for fieldName, points := range pointsByField {
-		for _, point := range points {
-			if !geometry.IsPointInPolygon(geometry.Point{Lat: point.Lat, Lon: point.Lon}, geometry.POLYGON) {
-				continue
-			}
+// cloudCoverHandler calculates the total cloud cover from the low, medium and high cloud cover (lcc, mcc, hcc),
+// it is missing when one of the layers is since a missing layer can't be told from a clear one.
+type cloudCoverHandler struct{}
 
-			coordKey := fmt.Sprintf("%.3f,%.3f", math.Round(point.Lon*1000)/1000, math.Round(point.Lat*1000)/1000)
+func (cloudCoverHandler) Inputs() []string {
+	return []string{"lcc", "mcc", "hcc"}
+}
 
-			if cloudDataMap[coordKey] == nil {
-				cloudDataMap[coordKey] = make(map[string]float64)
-			}
+func (cloudCoverHandler) Units() string {
+	return "%"
+}
 
-			value := 0.0
-			if point.Value < 9999 {
-				// AROME cloud cover values are typically in percentage format (0-100),
-				// but we handle fractional input (0-1) as well.
-				value = point.Value
-				if value > 1.0 {
-					value = value / 100.0 // Convert percentage to fraction
-				}
-			}
-			cloudDataMap[coordKey][fieldName] = value
+func (cloudCoverHandler) Compute(values Values) (float64, bool) {
+	fractions := make([]float64, 0, 3)
+	for _, field := range []string{"lcc", "mcc", "hcc"} {
+		fieldValue, ok := values.Get(field)
+		if !ok {
+			return 0, false
 		}
-	}
-
-	// Calculate total cloud cover using the extracted function
-	for coordKey, cloudData := range cloudDataMap {
-		lcc := cloudData["lcc"]
-		mcc := cloudData["mcc"]
-		hcc := cloudData["hcc"]
-
-		// Calculate total cloud cover
-		tcc := cloudCover(lcc, mcc, hcc)
-
-		// Parse coordinates from key
-		var lon, lat float64
-		fmt.Sscanf(coordKey, "%f,%f", &lon, &lat)
 
-		coordinateMap[coordKey] = geometry.GeoPoint{
-			Lat:   lat,
-			Lon:   lon,
-			Value: tcc,
+		// AROME cloud cover values are typically in percentage format (0-100),
+		// but we handle fractional input (0-1) as well.
+		if fieldValue > 1.0 {
+			fieldValue = fieldValue / 100.0
 		}
+		fractions = append(fractions, fieldValue)
 	}
 
-	return coordinateMap
+	return cloudCover(fractions[0], fractions[1], fractions[2]), true
 }
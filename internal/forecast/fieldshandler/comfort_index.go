@@ -1,10 +1,7 @@
 package fieldshandler
 
 import (
-	"fmt"
 	"math"
-
-	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
 )
 
 // comfortIndex calculates a comfort index on a scale of 1 to 10.
@@ -56,52 +53,27 @@ func comfortIndex(t2m, u10, v10, r2 float64) float64 {
 	return index
 }
 
-func ProcessComfortIndex(pointsByField map[string][]geometry.GeoPoint) map[string]geometry.GeoPoint {
-	weatherDataMap := make(map[string]map[string]float64)
-	coordinateMap := make(map[string]geometry.GeoPoint)
-
-	// Collect weather data by coordinate - only store valid values
-	for fieldName, points := range pointsByField {
-		for _, point := range points {
-			if !geometry.IsPointInPolygon(geometry.Point{Lat: point.Lat, Lon: point.Lon}, geometry.POLYGON) {
-				continue
-			}
+// comfortIndexHandler calculates the comfort index of the points where all of t2m, u10, v10 and r2 are known
+type comfortIndexHandler struct{}
 
-			// Only store values that are valid (< 9999)
-			if point.Value < 9999 {
-				coordKey := fmt.Sprintf("%.3f,%.3f", math.Round(point.Lon*1000)/1000, math.Round(point.Lat*1000)/1000)
+func (comfortIndexHandler) Inputs() []string {
+	return []string{"t2m", "u10", "v10", "r2"}
+}
 
-				if weatherDataMap[coordKey] == nil {
-					weatherDataMap[coordKey] = make(map[string]float64)
-				}
+func (comfortIndexHandler) Units() string {
+	return ""
+}
 
-				weatherDataMap[coordKey][fieldName] = point.Value
-			}
-		}
-	}
+func (comfortIndexHandler) Compute(values Values) (float64, bool) {
+	t2m, hasT2m := values.Get("t2m") // Temperature in Kelvin
+	u10, hasU10 := values.Get("u10") // U-component of wind at 10m (m/s)
+	v10, hasV10 := values.Get("v10") // V-component of wind at 10m (m/s)
+	r2, hasR2 := values.Get("r2")    // Relative humidity at 2m (%)
 
-	// Calculate comfort index for each coordinate
-	for coordKey, weatherData := range weatherDataMap {
-		t2m, hasT2m := weatherData["t2m"] // Temperature in Kelvin
-		u10, hasU10 := weatherData["u10"] // U-component of wind at 10m (m/s)
-		v10, hasV10 := weatherData["v10"] // V-component of wind at 10m (m/s)
-		r2, hasR2 := weatherData["r2"]   // Relative humidity at 2m (%)
-
-		// Only calculate if we have all required fields
-		if hasT2m && hasU10 && hasV10 && hasR2 {
-			comfort := comfortIndex(t2m, u10, v10, r2)
-
-			// Parse coordinates from key
-			var lon, lat float64
-			fmt.Sscanf(coordKey, "%f,%f", &lon, &lat)
-
-			coordinateMap[coordKey] = geometry.GeoPoint{
-				Lat:   lat,
-				Lon:   lon,
-				Value: comfort,
-			}
-		}
+	// Only calculate if we have all required fields
+	if !hasT2m || !hasU10 || !hasV10 || !hasR2 {
+		return 0, false
 	}
 
-	return coordinateMap
-} 
\ No newline at end of file
+	return comfortIndex(t2m, u10, v10, r2), true
+}
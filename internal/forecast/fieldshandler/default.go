@@ -1,44 +1,25 @@
 package fieldshandler
 
-import (
-	"fmt"
-	"math"
+// defaultHandler sums the values of all the fields, the sum is missing when one of them is
+type defaultHandler struct{}
 
-	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
-)
+func (defaultHandler) Inputs() []string {
+	return nil
+}
 
-// ProcessDefaultForecast handles the default behavior of summing values from all fields
-func ProcessDefaultForecast(pointsByField map[string][]geometry.GeoPoint) map[string]geometry.GeoPoint {
-	coordinateMap := make(map[string]geometry.GeoPoint)
-	
-	for _, points := range pointsByField {
-		for _, point := range points {
-			if !geometry.IsPointInPolygon(geometry.Point{Lat: point.Lat, Lon: point.Lon}, geometry.POLYGON) {
-				continue
-			}
-			
-			// Create a key from rounded coordinates for grouping
-			coordKey := fmt.Sprintf("%.3f,%.3f", math.Round(point.Lon*1000)/1000, math.Round(point.Lat*1000)/1000)
-			
-			value := 0.0
-			if point.Value < 9999 {
-				value = point.Value
-			}
-			
-			if existingPoint, exists := coordinateMap[coordKey]; exists {
-				// Sum the values if coordinate already exists
-				existingPoint.Value += value
-				coordinateMap[coordKey] = existingPoint
-			} else {
-				// Create new point entry
-				coordinateMap[coordKey] = geometry.GeoPoint{
-					Lat:   math.Round(point.Lat*1000)/1000,
-					Lon:   math.Round(point.Lon*1000)/1000,
-					Value: value,
-				}
-			}
+func (defaultHandler) Units() string {
+	return ""
+}
+
+func (defaultHandler) Compute(values Values) (float64, bool) {
+	sum := 0.0
+	for _, field := range values.Fields() {
+		value, ok := values.Get(field)
+		if !ok {
+			return 0, false
 		}
+		sum += value
 	}
-	
-	return coordinateMap
-} 
\ No newline at end of file
+
+	return sum, true
+}
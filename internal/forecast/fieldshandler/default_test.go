@@ -0,0 +1,40 @@
+package fieldshandler
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+func TestDefaultHandler(t *testing.T) {
+	// 2x2 grid from 40N 0E to 39N 1E, the area only contains the western column
+	definition := geometry.GridDefinition{Nx: 2, Ny: 2, FirstLat: 40, FirstLon: 0, DLat: -1, DLon: 1}
+	area := geometry.NewMaskedArea(geometry.MultiPolygon{
+		{{{Lat: 38.5, Lon: -0.5}, {Lat: 38.5, Lon: 0.5}, {Lat: 40.5, Lon: 0.5}, {Lat: 40.5, Lon: -0.5}}},
+	})
+	grids := map[string]*geometry.Grid{
+		"rain": {GridDefinition: definition, Values: []float32{1, 2, 3, 4}},
+		"snow": {GridDefinition: definition, Values: []float32{0.5, 0, 9999, 0}, Missing: []bool{false, false, true, false}},
+	}
+
+	points, err := Process(defaultHandler{}, grids, area)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []geometry.GeoPoint{
+		{Lat: 40, Lon: 0, Value: 1.5},
+		{Lat: 39, Lon: 0, Value: math.NaN()}, // The snow is missing so the sum is unknown, not 3
+	}
+	if len(points) != len(expected) {
+		t.Fatalf("Process(default) = %v; want %v", points, expected)
+	}
+	for i := range points {
+		same := points[i].Lat == expected[i].Lat && points[i].Lon == expected[i].Lon &&
+			(points[i].Value == expected[i].Value || math.IsNaN(points[i].Value) && math.IsNaN(expected[i].Value))
+		if !same {
+			t.Errorf("Process(default)[%d] = %v; want %v", i, points[i], expected[i])
+		}
+	}
+}
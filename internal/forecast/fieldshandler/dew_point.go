@@ -0,0 +1,45 @@
+package fieldshandler
+
+import (
+	"math"
+)
+
+// dewPoint calculates the dew point in °C from the temperature (tC in °C) and the relative humidity (rh in %)
+// with the Magnus formula and the coefficients of Sonntag (1990), which are valid from -45°C to 60°C.
+// https://en.wikipedia.org/wiki/Dew_point#Calculating_the_dew_point
+//
+// It returns false out of that range, or when there is no humidity at all (the dew point is then undefined).
+func dewPoint(tC, rh float64) (float64, bool) {
+	if tC < -45 || tC > 60 || rh <= 0 {
+		return 0, false
+	}
+
+	// Models sometimes slightly exceed saturation
+	rh = math.Min(rh, 100)
+
+	const a, b = 17.62, 243.12
+	gamma := math.Log(rh/100) + a*tC/(b+tC)
+
+	return b * gamma / (a - gamma), true
+}
+
+// dewPointHandler calculates the dew point at 2m from t2m and r2
+type dewPointHandler struct{}
+
+func (dewPointHandler) Inputs() []string {
+	return []string{"t2m", "r2"}
+}
+
+func (dewPointHandler) Units() string {
+	return "°C"
+}
+
+func (dewPointHandler) Compute(values Values) (float64, bool) {
+	t2m, hasT2m := values.Get("t2m") // Temperature in Kelvin
+	r2, hasR2 := values.Get("r2")    // Relative humidity at 2m (%)
+	if !hasT2m || !hasR2 {
+		return 0, false
+	}
+
+	return dewPoint(t2m-273.15, r2)
+}
@@ -0,0 +1,81 @@
+package fieldshandler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDewPoint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tC       float64
+		rh       float64
+		expected float64
+	}{
+		{
+			name:     "Mild and moist",
+			tC:       20,
+			rh:       50,
+			expected: 9.26,
+		},
+		{
+			name:     "Freezing",
+			tC:       0,
+			rh:       80,
+			expected: -3.04,
+		},
+		{
+			name:     "Hot and humid",
+			tC:       30,
+			rh:       70,
+			expected: 23.93,
+		},
+		{
+			name:     "Cold",
+			tC:       -10,
+			rh:       60,
+			expected: -16.31,
+		},
+		{
+			name:     "Saturated air",
+			tC:       35,
+			rh:       100,
+			expected: 35.0,
+		},
+		{
+			name:     "Very dry air",
+			tC:       25,
+			rh:       1,
+			expected: -34.99,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, ok := dewPoint(tc.tC, tc.rh)
+			if !ok || math.Abs(result-tc.expected) > 0.01 { // Using a tolerance for float comparison
+				t.Errorf("dewPoint(%f, %f) = %f, %t; want %f", tc.tC, tc.rh, result, ok, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDewPointOutOfRange(t *testing.T) {
+	testCases := []struct {
+		name string
+		tC   float64
+		rh   float64
+	}{
+		{name: "Too cold", tC: -50, rh: 50},
+		{name: "Too hot", tC: 61, rh: 50},
+		{name: "No humidity", tC: 20, rh: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result, ok := dewPoint(tc.tC, tc.rh); ok {
+				t.Errorf("dewPoint(%f, %f) = %f; want no value", tc.tC, tc.rh, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package fieldshandler
+
+import (
+	"math"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// value returns the value of a field at point i, and false when the field or the value is missing
+func value(grids map[string]*geometry.Grid, field string, i int) (float64, bool) {
+	grid, exists := grids[field]
+	if !exists || grid.IsMissing(i) {
+		return 0, false
+	}
+
+	return float64(grid.Values[i]), true
+}
+
+// newPoint is point i of a grid with its coordinates rounded to 3 decimals
+func newPoint(definition geometry.GridDefinition, i int, value float64) geometry.GeoPoint {
+	point := definition.Point(i)
+
+	return geometry.GeoPoint{
+		Lat:   math.Round(point.Lat*1000) / 1000,
+		Lon:   math.Round(point.Lon*1000) / 1000,
+		Value: value,
+	}
+}
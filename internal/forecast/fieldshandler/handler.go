@@ -0,0 +1,131 @@
+package fieldshandler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// Handler turns the GRIB fields of a forecast group into the single value served for each point.
+// Adding a derived product is a matter of implementing it and adding it to handlers.
+type Handler interface {
+	// Inputs are the keys of the fields the handler needs, nil when it takes whatever fields it is given
+	Inputs() []string
+	// Units of the computed value, empty when they are those of the fields
+	Units() string
+	// Compute returns the value of a point from the values of the fields there,
+	// false when it can't be computed (a field is missing there, or the value is out of the domain of the formula)
+	Compute(values Values) (float64, bool)
+}
+
+// VectorHandler is a Handler also serving the components of a vector for each point, after its value
+type VectorHandler interface {
+	Handler
+	// Components are only used for the points Compute returned a value for
+	Components(values Values) []float64
+}
+
+// handlers are the handlers forecast groups can refer to by name in the configuration
+var handlers = map[string]Handler{
+	"default":        defaultHandler{},
+	"cloud_cover":    cloudCoverHandler{},
+	"comfort_index":  comfortIndexHandler{},
+	"wind_speed":     windSpeedHandler{},
+	"wind_direction": windDirectionHandler{},
+	"wind_gust":      windGustHandler{},
+	"wind":           windVectorHandler{},
+	"dew_point":      dewPointHandler{},
+	"humidex":        humidexHandler{},
+	"heat_index":     heatIndexHandler{},
+	"wind_chill":     windChillHandler{},
+}
+
+// Lookup returns the handler registered under name
+func Lookup(name string) (Handler, bool) {
+	handler, exists := handlers[name]
+	return handler, exists
+}
+
+// Names returns the names of every handler, sorted
+func Names() []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Values are the values of the fields of a forecast group at a single point
+type Values struct {
+	grids  map[string]*geometry.Grid
+	fields []string
+	i      int
+}
+
+// Get returns the value of a field, and false when the field or its value is missing
+func (v Values) Get(field string) (float64, bool) {
+	return value(v.grids, field, v.i)
+}
+
+// Fields returns the keys of the fields of the forecast group, sorted
+func (v Values) Fields() []string {
+	return v.fields
+}
+
+// Process runs a handler on every point of the area. The fields are combined point by point,
+// so they must all be on the same grid. Points without a value are kept with NaN, see geometry.GeoPoint.
+func Process(handler Handler, grids map[string]*geometry.Grid, area *geometry.MaskedArea) ([]geometry.GeoPoint, error) {
+	fields := make([]string, 0, len(grids))
+	for field := range grids {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if len(fields) == 0 {
+		return nil, errors.New("no field to process")
+	}
+	for _, input := range handler.Inputs() {
+		if _, exists := grids[input]; !exists {
+			return nil, fmt.Errorf("field %q is required", input)
+		}
+	}
+
+	definition := grids[fields[0]].GridDefinition
+	for _, field := range fields[1:] {
+		if grids[field].GridDefinition != definition {
+			return nil, fmt.Errorf("field %q is not on the same grid as %q", field, fields[0])
+		}
+	}
+
+	indexes := area.Inside(definition)
+	points := make([]geometry.GeoPoint, 0, len(indexes))
+	values := Values{grids: grids, fields: fields}
+
+	vectorHandler, isVector := handler.(VectorHandler)
+
+	for _, i := range indexes {
+		values.i = i
+		computed, ok := handler.Compute(values)
+		if !ok {
+			computed = math.NaN()
+		}
+
+		point := newPoint(definition, i, computed)
+		if isVector {
+			point.Components = vectorHandler.Components(values)
+			if !ok {
+				for c := range point.Components {
+					point.Components[c] = math.NaN()
+				}
+			}
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
@@ -0,0 +1,91 @@
+package fieldshandler
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+func TestProcess(t *testing.T) {
+	definition := geometry.GridDefinition{Nx: 2, Ny: 1, FirstLat: 40, FirstLon: 0, DLat: -1, DLon: 1}
+	area := geometry.NewMaskedArea(geometry.MultiPolygon{
+		{{{Lat: 39.5, Lon: -0.5}, {Lat: 39.5, Lon: 1.5}, {Lat: 40.5, Lon: 1.5}, {Lat: 40.5, Lon: -0.5}}},
+	})
+	grid := func(values ...float32) *geometry.Grid {
+		return &geometry.Grid{GridDefinition: definition, Values: values}
+	}
+
+	testCases := []struct {
+		name    string
+		handler Handler
+		grids   map[string]*geometry.Grid
+		// expected is the number of points with a value
+		expected int
+		err      string
+	}{
+		{
+			name:    "Comfort index has no value where a field is missing",
+			handler: comfortIndexHandler{},
+			grids: map[string]*geometry.Grid{
+				"t2m": grid(293.15, 293.15),
+				"u10": grid(2, 2),
+				"v10": grid(2, 2),
+				"r2":  {GridDefinition: definition, Values: []float32{50, 9999}, Missing: []bool{false, true}},
+			},
+			expected: 1,
+		},
+		{
+			name:    "Cloud cover has no value where a layer is missing",
+			handler: cloudCoverHandler{},
+			grids: map[string]*geometry.Grid{
+				"lcc": grid(0, 0),
+				"mcc": {GridDefinition: definition, Values: []float32{9999, 0}, Missing: []bool{true, false}},
+				"hcc": grid(0, 0),
+			},
+			expected: 1,
+		},
+		{
+			name:    "Missing input",
+			handler: comfortIndexHandler{},
+			grids:   map[string]*geometry.Grid{"t2m": grid(293.15, 293.15)},
+			err:     `field "u10" is required`,
+		},
+		{
+			name:    "Fields on different grids",
+			handler: defaultHandler{},
+			grids: map[string]*geometry.Grid{
+				"rain": grid(1, 2),
+				"snow": {GridDefinition: geometry.GridDefinition{Nx: 2, Ny: 1, FirstLat: 41, FirstLon: 0, DLat: -1, DLon: 1}, Values: []float32{1, 2}},
+			},
+			err: `field "snow" is not on the same grid as "rain"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			points, err := Process(tc.handler, tc.grids, area)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Process() error = %v; want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Points without a value are kept with NaN
+			withValue := 0
+			for _, point := range points {
+				if !math.IsNaN(point.Value) {
+					withValue++
+				}
+			}
+			if len(points) != 2 || withValue != tc.expected {
+				t.Errorf("Process() = %v; want 2 points, %d with a value", points, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package fieldshandler
+
+import (
+	"math"
+)
+
+// heatIndex calculates the heat index of the US National Weather Service in °C from the temperature (tC in °C)
+// and the relative humidity (rh in %), with the Rothfusz regression and its adjustments.
+// https://www.wpc.ncep.noaa.gov/html/heatindex_equation.shtml
+//
+// The heat index is only defined from 80°F (26.7°C) up, the temperature is returned below.
+func heatIndex(tC, rh float64) float64 {
+	t := tC*9/5 + 32
+	if t < 80 {
+		return tC
+	}
+
+	rh = math.Max(0, math.Min(100, rh))
+
+	// The simple formula is enough when the heat index is below 80°F
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+			0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+		if rh < 13 && t <= 112 {
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		} else if rh > 85 && t <= 87 {
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+// heatIndexHandler calculates the heat index from t2m and r2
+type heatIndexHandler struct{}
+
+func (heatIndexHandler) Inputs() []string {
+	return []string{"t2m", "r2"}
+}
+
+func (heatIndexHandler) Units() string {
+	return "°C"
+}
+
+func (heatIndexHandler) Compute(values Values) (float64, bool) {
+	t2m, hasT2m := values.Get("t2m") // Temperature in Kelvin
+	r2, hasR2 := values.Get("r2")    // Relative humidity at 2m (%)
+	if !hasT2m || !hasR2 {
+		return 0, false
+	}
+
+	return heatIndex(t2m-273.15, r2), true
+}
@@ -0,0 +1,61 @@
+package fieldshandler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHeatIndex(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tC       float64
+		rh       float64
+		expected float64
+	}{
+		{
+			name:     "Hot and humid",
+			tC:       30,
+			rh:       70,
+			expected: 35.04,
+		},
+		{
+			name:     "Very hot",
+			tC:       35,
+			rh:       50,
+			expected: 40.68,
+		},
+		{
+			name:     "Just above 80°F",
+			tC:       27,
+			rh:       40,
+			expected: 26.86,
+		},
+		{
+			name:     "Very dry adjustment",
+			tC:       40,
+			rh:       10,
+			expected: 36.71,
+		},
+		{
+			name:     "Very humid adjustment",
+			tC:       28,
+			rh:       90,
+			expected: 34.0,
+		},
+		{
+			name:     "Below 80°F",
+			tC:       25,
+			rh:       90,
+			expected: 25,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := heatIndex(tc.tC, tc.rh)
+			if math.Abs(result-tc.expected) > 0.01 { // Using a tolerance for float comparison
+				t.Errorf("heatIndex(%f, %f) = %f; want %f", tc.tC, tc.rh, result, tc.expected)
+			}
+		})
+	}
+}
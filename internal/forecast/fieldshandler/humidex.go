@@ -0,0 +1,48 @@
+package fieldshandler
+
+import (
+	"math"
+)
+
+// humidex calculates the humidex of Environment Canada from the temperature (tC in °C) and the dew point (tdC in °C).
+// https://en.wikipedia.org/wiki/Humidex
+//
+// The humidex describes how humid heat feels, it is only used from 20°C up. Below, or when the air is so dry
+// that the humidex would be lower than the temperature, the temperature is returned.
+func humidex(tC, tdC float64) float64 {
+	if tC < 20 {
+		return tC
+	}
+
+	// Vapour pressure in hPa
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+tdC)))
+
+	return math.Max(tC, tC+0.5555*(e-10))
+}
+
+// humidexHandler calculates the humidex from t2m and r2
+type humidexHandler struct{}
+
+func (humidexHandler) Inputs() []string {
+	return []string{"t2m", "r2"}
+}
+
+func (humidexHandler) Units() string {
+	return "°C"
+}
+
+func (humidexHandler) Compute(values Values) (float64, bool) {
+	t2m, hasT2m := values.Get("t2m") // Temperature in Kelvin
+	r2, hasR2 := values.Get("r2")    // Relative humidity at 2m (%)
+	if !hasT2m || !hasR2 {
+		return 0, false
+	}
+
+	tC := t2m - 273.15
+	tdC, ok := dewPoint(tC, r2)
+	if !ok {
+		return 0, false
+	}
+
+	return humidex(tC, tdC), true
+}
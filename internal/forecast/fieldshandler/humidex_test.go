@@ -0,0 +1,55 @@
+package fieldshandler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHumidex(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tC       float64
+		tdC      float64
+		expected float64
+	}{
+		{
+			name:     "Hot and humid",
+			tC:       30,
+			tdC:      23.93, // 70% humidity
+			expected: 41.2,
+		},
+		{
+			name:     "Very hot",
+			tC:       35,
+			tdC:      23.02, // 50% humidity
+			expected: 45.29,
+		},
+		{
+			name:     "Warm and humid",
+			tC:       25,
+			tdC:      21.31, // 80% humidity
+			expected: 33.69,
+		},
+		{
+			name:     "Dry heat",
+			tC:       22,
+			tdC:      -2.02, // 20% humidity, the humidex would be lower than the temperature
+			expected: 22,
+		},
+		{
+			name:     "Below 20°C",
+			tC:       15,
+			tdC:      12,
+			expected: 15,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := humidex(tc.tC, tc.tdC)
+			if math.Abs(result-tc.expected) > 0.01 { // Using a tolerance for float comparison
+				t.Errorf("humidex(%f, %f) = %f; want %f", tc.tC, tc.tdC, result, tc.expected)
+			}
+		})
+	}
+}
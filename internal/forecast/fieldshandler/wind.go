@@ -0,0 +1,87 @@
+package fieldshandler
+
+import (
+	"math"
+)
+
+// windSpeed is the magnitude of the wind in m/s from its u (eastward) and v (northward) components
+func windSpeed(u, v float64) float64 {
+	return math.Hypot(u, v)
+}
+
+// windDirection is the meteorological direction of the wind, the one it blows from, in degrees
+// clockwise from the north in [0, 360). A calm wind has no direction and gets 0.
+func windDirection(u, v float64) float64 {
+	if u == 0 && v == 0 {
+		return 0
+	}
+
+	// atan2 is in (-180, 180], a northerly wind gives -0
+	return math.Mod(math.Atan2(-u, -v)*180/math.Pi+360, 360)
+}
+
+// windComponents reads the u10 and v10 fields of a point
+func windComponents(values Values) (float64, float64, bool) {
+	u10, hasU10 := values.Get("u10")
+	v10, hasV10 := values.Get("v10")
+
+	return u10, v10, hasU10 && hasV10
+}
+
+// windSpeedHandler calculates the wind speed at 10m from u10 and v10
+type windSpeedHandler struct{}
+
+func (windSpeedHandler) Inputs() []string {
+	return []string{"u10", "v10"}
+}
+
+func (windSpeedHandler) Units() string {
+	return "m/s"
+}
+
+func (windSpeedHandler) Compute(values Values) (float64, bool) {
+	u10, v10, ok := windComponents(values)
+	return windSpeed(u10, v10), ok
+}
+
+// windDirectionHandler calculates the direction the wind at 10m blows from, from u10 and v10
+type windDirectionHandler struct{}
+
+func (windDirectionHandler) Inputs() []string {
+	return []string{"u10", "v10"}
+}
+
+func (windDirectionHandler) Units() string {
+	return "°"
+}
+
+func (windDirectionHandler) Compute(values Values) (float64, bool) {
+	u10, v10, ok := windComponents(values)
+	return windDirection(u10, v10), ok
+}
+
+// windGustHandler serves the gust speed of the packages that provide it, like 10fg
+type windGustHandler struct{}
+
+func (windGustHandler) Inputs() []string {
+	return []string{"gust"}
+}
+
+func (windGustHandler) Units() string {
+	return "m/s"
+}
+
+func (windGustHandler) Compute(values Values) (float64, bool) {
+	return values.Get("gust")
+}
+
+// windVectorHandler serves the wind speed along with the u10 and v10 components,
+// for the frontend to draw arrows or streamlines
+type windVectorHandler struct {
+	windSpeedHandler
+}
+
+func (windVectorHandler) Components(values Values) []float64 {
+	u10, v10, _ := windComponents(values)
+	return []float64{u10, v10}
+}
@@ -0,0 +1,41 @@
+package fieldshandler
+
+import (
+	"math"
+)
+
+// windChill calculates the wind chill index in °C used by Environment Canada and the US National Weather Service
+// from the temperature (tC in °C) and the wind speed at 10m (windSpeed in m/s).
+// https://en.wikipedia.org/wiki/Wind_chill#North_American_and_United_Kingdom_wind_chill_index
+//
+// The wind chill is only defined at 10°C or below with a wind of at least 4.8 km/h, the temperature is returned otherwise.
+func windChill(tC, windSpeed float64) float64 {
+	// The formula takes km/h
+	v := windSpeed * 3.6
+	if tC > 10 || v < 4.8 {
+		return tC
+	}
+
+	return 13.12 + 0.6215*tC - 11.37*math.Pow(v, 0.16) + 0.3965*tC*math.Pow(v, 0.16)
+}
+
+// windChillHandler calculates the wind chill from t2m, u10 and v10
+type windChillHandler struct{}
+
+func (windChillHandler) Inputs() []string {
+	return []string{"t2m", "u10", "v10"}
+}
+
+func (windChillHandler) Units() string {
+	return "°C"
+}
+
+func (windChillHandler) Compute(values Values) (float64, bool) {
+	t2m, hasT2m := values.Get("t2m") // Temperature in Kelvin
+	u10, v10, hasWind := windComponents(values)
+	if !hasT2m || !hasWind {
+		return 0, false
+	}
+
+	return windChill(t2m-273.15, windSpeed(u10, v10)), true
+}
@@ -0,0 +1,61 @@
+package fieldshandler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindChill(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tC        float64
+		windSpeed float64
+		expected  float64
+	}{
+		{
+			name:      "Cold and windy",
+			tC:        -10,
+			windSpeed: 5, // 18 km/h
+			expected:  -17.45,
+		},
+		{
+			name:      "Freezing with strong wind",
+			tC:        0,
+			windSpeed: 10, // 36 km/h
+			expected:  -7.05,
+		},
+		{
+			name:      "Light wind",
+			tC:        5,
+			windSpeed: 2, // 7.2 km/h
+			expected:  3.35,
+		},
+		{
+			name:      "Severe cold",
+			tC:        -20,
+			windSpeed: 15, // 54 km/h
+			expected:  -35.85,
+		},
+		{
+			name:      "Above 10°C",
+			tC:        12,
+			windSpeed: 10,
+			expected:  12,
+		},
+		{
+			name:      "Below 4.8 km/h",
+			tC:        -5,
+			windSpeed: 1, // 3.6 km/h
+			expected:  -5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := windChill(tc.tC, tc.windSpeed)
+			if math.Abs(result-tc.expected) > 0.01 { // Using a tolerance for float comparison
+				t.Errorf("windChill(%f, %f) = %f; want %f", tc.tC, tc.windSpeed, result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package fieldshandler
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+func TestWind(t *testing.T) {
+	testCases := []struct {
+		name      string
+		u10       float64
+		v10       float64
+		speed     float64
+		direction float64
+	}{
+		{
+			name:      "Northerly wind",
+			u10:       0,
+			v10:       -5, // Blowing southward
+			speed:     5,
+			direction: 0,
+		},
+		{
+			name:      "Easterly wind",
+			u10:       -3,
+			v10:       0,
+			speed:     3,
+			direction: 90,
+		},
+		{
+			name:      "Southerly wind",
+			u10:       0,
+			v10:       4,
+			speed:     4,
+			direction: 180,
+		},
+		{
+			name:      "Westerly wind",
+			u10:       2,
+			v10:       0,
+			speed:     2,
+			direction: 270,
+		},
+		{
+			name:      "South-westerly wind",
+			u10:       3,
+			v10:       3,
+			speed:     4.243,
+			direction: 225,
+		},
+		{
+			name:      "North-westerly wind",
+			u10:       1,
+			v10:       -1,
+			speed:     1.414,
+			direction: 315,
+		},
+		{
+			name:      "Calm",
+			u10:       0,
+			v10:       0,
+			speed:     0,
+			direction: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if speed := windSpeed(tc.u10, tc.v10); math.Abs(speed-tc.speed) > 0.001 {
+				t.Errorf("windSpeed(%f, %f) = %f; want %f", tc.u10, tc.v10, speed, tc.speed)
+			}
+			if direction := windDirection(tc.u10, tc.v10); math.Abs(direction-tc.direction) > 0.001 {
+				t.Errorf("windDirection(%f, %f) = %f; want %f", tc.u10, tc.v10, direction, tc.direction)
+			}
+		})
+	}
+}
+
+func TestWindVector(t *testing.T) {
+	definition := geometry.GridDefinition{Nx: 1, Ny: 1, FirstLat: 40, FirstLon: 0, DLat: -1, DLon: 1}
+	area := geometry.NewMaskedArea(geometry.MultiPolygon{
+		{{{Lat: 39.5, Lon: -0.5}, {Lat: 39.5, Lon: 0.5}, {Lat: 40.5, Lon: 0.5}, {Lat: 40.5, Lon: -0.5}}},
+	})
+	grids := map[string]*geometry.Grid{
+		"u10": {GridDefinition: definition, Values: []float32{3}},
+		"v10": {GridDefinition: definition, Values: []float32{-4}},
+	}
+
+	points, err := Process(windVectorHandler{}, grids, area)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []geometry.GeoPoint{{Lat: 40, Lon: 0, Value: 5, Components: []float64{3, -4}}}
+	if !reflect.DeepEqual(points, expected) {
+		t.Errorf("Process(wind) = %v; want %v", points, expected)
+	}
+}
@@ -2,12 +2,7 @@ package forecast
 
 import (
 	"fmt"
-	"io"
 	"math"
-	"net/http"
-	"os"
-	"sync"
-	"time"
 
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/forecast/fieldshandler"
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
@@ -17,205 +12,140 @@ import (
 )
 
 type ForecastGroup struct {
-	CommonName string
-	Fields []string
+	CommonName string `json:"name"`
+	// Fields select the GRIB messages the handler needs, handlers know them by their key
+	Fields []grib.Selector `json:"fields"`
+	// Handler is the name of the fieldshandler.Handler computing the served value from the fields
+	Handler string `json:"handler"`
+	// Window makes the group serve the difference of its fields over the last Window hours,
+	// for fields accumulated since the start of the run like tirf
+	Window int `json:"window"`
+	// Optional groups are skipped when the package doesn't provide their fields, instead of failing the hour
+	Optional bool `json:"optional"`
 }
 
 type ForecastPackage struct {
-	Package string
-	Forecasts []ForecastGroup
+	Package   string          `json:"package"`
+	Forecasts []ForecastGroup `json:"forecasts"`
 }
 
 const (
 	FORECAST_HOURS = 51
 )
 
-var FORECAST_PACKAGES = []ForecastPackage{
-	{
-		Package: "SP2",
-		Forecasts: []ForecastGroup{
-			{CommonName: "rainfall_accumulation", Fields: []string{"tirf"}},
-			{CommonName: "cloud_cover", Fields: []string{"lcc", "mcc", "hcc"}},
-		},
-	},
-	{
-		Package: "SP1",
-		Forecasts: []ForecastGroup{
-			{CommonName: "humidity", Fields: []string{"r2"}},
-			{CommonName: "temperature", Fields: []string{"t2m"}},
-			{CommonName: "comfort_index", Fields: []string{"r2", "t2m", "u10", "v10"}},
-		},
-	},
-}
-
-func StartFetching() {
-	var wg sync.WaitGroup
-	
-	for _, forecastPackage := range FORECAST_PACKAGES {
-		wg.Add(1)
-		go func(fp ForecastPackage) {
-			defer wg.Done()
-			processForecastPackage(fp)
-		}(forecastPackage)
-	}
-	
-	wg.Wait()
-
-	StartFetching()
-}
-
-// Package is like SP1 or SP2 from méteo-france
-// Each package is stored in a separate folder in data.gouv.fr
-// So we download every hour of every package
-func processForecastPackage(forecastPackage ForecastPackage) {
-	run := getLatestCompleteRun(forecastPackage)
-
-	if storage.IsUpToDate(forecastPackage.Package, run) {
-		utils.Log("Forecast already downloaded, skipping " + run)
-		time.Sleep(60 * time.Second)
-		return
+// processForecastGroup decodes the fields needed by all the forecast groups of a package in a single pass over the file.
+// Groups with a window are processed for the hours whose window is complete (see processWindows), which are returned by group name.
+// Optional groups whose fields the file doesn't have are skipped, their names are returned so that they aren't published.
+func processForecastGroup(filename string, forecastPackage ForecastPackage, regions []Region, run string, hour string, history *accumulations) (map[string][]string, []string, error) {
+	grids, err := grib.ExtractGribData(filename, packageSelectors(forecastPackage), regionsBounds(regions))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	utils.Log("Forecast found for package " + forecastPackage.Package + " run: " + run)
-
-	// Process each hour from 1 to 51
-	for _, hour := range getAvailableHours() {
-		filename, err := downloadPackage(forecastPackage.Package, run, hour)
-		if err != nil {
-			utils.Log("Error getting single forecast: " + err.Error())
-			return
+	skipped := []string{}
+	for _, forecastGroup := range forecastPackage.Forecasts {
+		if forecastGroup.Window > 0 {
+			continue
 		}
 
-		utils.Log("Forecast retrieved for " + run + " " + hour)
-
-		// Now we process each param (temperature, humidity) of a given package
-		processForecastGroup(filename, forecastPackage, run, hour)
-	}
+		if field, missing := missingField(grids, forecastGroup); missing {
+			utils.Log(fmt.Sprintf("Skipping %s for %s %s, the file has no %q field", forecastGroup.CommonName, run, hour, field))
+			skipped = append(skipped, forecastGroup.CommonName)
+			continue
+		}
 
-	// Extract common names from forecast groups
-	commonNames := make([]string, len(forecastPackage.Forecasts))
-	for i, fg := range forecastPackage.Forecasts {
-		commonNames[i] = fg.CommonName
+		if _, err := ProcessSingleForecast(grids, forecastGroup, regions, run, hour); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", forecastGroup.CommonName, err)
+		}
 	}
-	
-	storage.RollOut(forecastPackage.Package, commonNames)
-}
 
-func processForecastGroup(filename string, forecastPackage ForecastPackage, run string, hour string) {
-	for _, forecastGroup := range forecastPackage.Forecasts {
-		ProcessSingleForecast(filename, forecastGroup.CommonName, forecastGroup.Fields, run, hour)
-	}
+	windows, err := processWindows(grids, forecastPackage, regions, run, hour, history)
+	return windows, skipped, err
 }
 
-func ProcessSingleForecast(filename string, commonName string, fields []string, dt string, hour string) (string, error) {
-	pointsByField, err := grib.ExtractGribData(filename, fields)
-	if err != nil {
-		utils.Log("Error extracting GRIB data: " + err.Error())
-		return "", err
-	}
+// packageSelectors is the union of the fields of all the forecast groups of a package,
+// a key used by several groups always has the same selector (see Config.validate).
+// Fields only needed by optional groups are optional.
+func packageSelectors(forecastPackage ForecastPackage) []grib.Selector {
+	selectors := []grib.Selector{}
+	keys := make(map[string]int)
 
-	// Process data based on forecast type
-	var coordinateMap map[string]geometry.GeoPoint
-	
-	switch commonName {
-	case "cloud_cover":
-		coordinateMap = fieldshandler.ProcessCloudCover(pointsByField)
-	case "comfort_index":
-		coordinateMap = fieldshandler.ProcessComfortIndex(pointsByField)
-	default:
-		coordinateMap = fieldshandler.ProcessDefaultForecast(pointsByField)
-	}
-	
-	// Convert coordinate map to output format
-	allData := [][]float64{}
-	for _, point := range coordinateMap {
-		allData = append(allData, []float64{point.Lon, point.Lat, math.Round(point.Value*100)/100})
+	for _, forecastGroup := range forecastPackage.Forecasts {
+		for _, field := range forecastGroup.Fields {
+			i, exists := keys[field.Key()]
+			if !exists {
+				i = len(selectors)
+				keys[field.Key()] = i
+				field.Optional = true
+				selectors = append(selectors, field)
+			}
+			selectors[i].Optional = selectors[i].Optional && forecastGroup.Optional
+		}
 	}
 
-	storage.Save(allData, commonName, hour, dt)
-
-	return "", nil
+	return selectors
 }
 
-func getAvailableRunDates() []string {
-	availableRunDates := []string{}
-	now := time.Now()
-	currentHour := now.Hour()
-	yesterday := now.AddDate(0, 0, -1)
-
-	runs := []int{21, 18, 15, 12, 9, 6, 3, 0}
-	
-	// Add available runs from current day
-	for _, run := range runs {
-		if run <= currentHour {
-			availableRunDates = append(availableRunDates,
-				now.Format("2006-01-02")+fmt.Sprintf("T%02d:00:00Z", run))
+// missingField returns the first field of a group the file didn't provide, only optional groups can miss one
+func missingField(grids map[string]*geometry.Grid, forecastGroup ForecastGroup) (string, bool) {
+	for _, field := range forecastGroup.Fields {
+		if grids[field.Key()] == nil {
+			return field.Key(), true
 		}
 	}
 
-	// Add all runs from previous day (in early morning only runs from past day are available)
-	for _, run := range runs {
-		availableRunDates = append(availableRunDates,
-			yesterday.Format("2006-01-02")+fmt.Sprintf("T%02d:00:00Z", run))
-	}
-
-	return availableRunDates
+	return "", false
 }
 
-func allForecastsHoursAreAvailable(packageName string, dt string) bool {
-	hours := getAvailableHours()
-
-	resultChans := make([]chan bool, len(hours))
-	for i, hour := range hours {
-		resultChans[i] = make(chan bool, 1)
-		go func(h string, ch chan bool) {
-			url := fmt.Sprintf("https://object.files.data.gouv.fr/meteofrance-pnt/pnt/%s/arome/001/%s/arome__001__%s__%sH__%s.grib2", dt, packageName, packageName, h, dt)
-			response, err := http.Head(url)
-			if err != nil {
-				ch <- false
-				return
-			}
-			ch <- response.StatusCode == 200
-		}(hour, resultChans[i])
+// regionsBounds is the bounding box of all the regions, only that part of the grids is decoded
+func regionsBounds(regions []Region) *geometry.BoundingBox {
+	if len(regions) == 0 {
+		return nil
 	}
 
-	allAvailable := true
-	for _, ch := range resultChans {
-		if !<-ch {
-			allAvailable = false
-			break
-		}
+	bounds := regions[0].Mask.Bounds()
+	for _, region := range regions[1:] {
+		bounds = bounds.Union(region.Mask.Bounds())
 	}
 
-	return allAvailable
+	return &bounds
 }
 
-func downloadPackage(packageName string, dt string, hour string) (string, error) {
-	url := fmt.Sprintf("https://object.files.data.gouv.fr/meteofrance-pnt/pnt/%s/arome/001/%s/arome__001__%s__%sH__%s.grib2", dt, packageName, packageName, hour, dt)
-	utils.Log("Downloading " + url)
-
-	response, err := http.Get(url)
-	if err != nil {
-		return "", err
+// ProcessSingleForecast runs the handler of a forecast group on the decoded fields of a package and saves the result for every region
+func ProcessSingleForecast(packageGrids map[string]*geometry.Grid, forecastGroup ForecastGroup, regions []Region, dt string, hour string) (string, error) {
+	// Handlers only get the fields of their group
+	grids := make(map[string]*geometry.Grid, len(forecastGroup.Fields))
+	for _, field := range forecastGroup.Fields {
+		grids[field.Key()] = packageGrids[field.Key()]
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode == 404 {
-		return "", fmt.Errorf("file not found: %s", url)
+	handler, exists := fieldshandler.Lookup(forecastGroup.Handler)
+	if !exists {
+		return "", fmt.Errorf("unknown handler %q for %s", forecastGroup.Handler, forecastGroup.CommonName)
 	}
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return "", err
-	}
+	for _, region := range regions {
+		points, err := fieldshandler.Process(handler, grids, region.Mask)
+		if err != nil {
+			return "", err
+		}
 
-	grib2file := fmt.Sprintf("./tmp/file_%s_%s_%s.grib2", packageName, dt, hour)
-	err = os.WriteFile(grib2file, body, 0644)
-	if err != nil {
-		return "", err
+		// Convert points to output format
+		allData := make([][]float64, 0, len(points))
+		for _, point := range points {
+			row := []float64{point.Lon, point.Lat, math.Round(point.Value*100)/100}
+			for _, component := range point.Components {
+				row = append(row, math.Round(component*100)/100)
+			}
+			allData = append(allData, row)
+		}
+
+		if _, err := storage.Save(allData, region.Name, forecastGroup.CommonName, hour, dt, handler.Units()); err != nil {
+			return "", err
+		}
 	}
 
-	return grib2file, nil
+	return "", nil
 }
 
 func getAvailableHours() []string {
@@ -227,17 +157,3 @@ func getAvailableHours() []string {
 
 	return hours
 }
-
-func getLatestCompleteRun(forecastPackage ForecastPackage) string {
-	latestCompleteRun := ""
-	for _, run := range getAvailableRunDates() {
-		if allForecastsHoursAreAvailable(forecastPackage.Package, run) {
-			latestCompleteRun = run
-			break
-		}
-	}
-
-	return latestCompleteRun
-}
-
-
@@ -0,0 +1,36 @@
+package forecast
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPackageSelectorsDecodesSharedFieldsOnce(t *testing.T) {
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, forecastPackage := range config.Packages {
+		if forecastPackage.Package != "SP1" {
+			continue
+		}
+
+		keys, optional := []string{}, []string{}
+		for _, selector := range packageSelectors(forecastPackage) {
+			keys = append(keys, selector.Key())
+			if selector.Optional {
+				optional = append(optional, selector.Key())
+			}
+		}
+
+		// humidity and temperature need r2 and t2m, which comfort_index needs as well
+		if expected := []string{"r2", "t2m", "u10", "v10", "gust"}; !slices.Equal(keys, expected) {
+			t.Errorf("packageSelectors(SP1) = %v; want %v", keys, expected)
+		}
+		// Only wind_gust needs gust, and it is optional
+		if expected := []string{"gust"}; !slices.Equal(optional, expected) {
+			t.Errorf("optional selectors of SP1 = %v; want %v", optional, expected)
+		}
+	}
+}
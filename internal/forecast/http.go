@@ -1,35 +1,112 @@
 package forecast
 
 import (
+	"encoding/json"
+	"errors"
+	"maps"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/storage"
 )
 
-func Serve() {
-	for _, forecastPackage := range FORECAST_PACKAGES {
+func Serve(config *Config) {
+	for _, forecastPackage := range config.Packages {
 		for _, forecastGroup := range forecastPackage.Forecasts {
-			http.HandleFunc("/" + forecastGroup.CommonName + ".json", func(w http.ResponseWriter, r *http.Request) {
-				// Get the hour from the request
-				hour := r.URL.Query().Get("hour")
+			for _, region := range config.Regions {
+				http.HandleFunc("/" + region.Name + "/" + forecastGroup.CommonName + ".json", serveForecast(region.Name, forecastGroup.CommonName))
+			}
+
+			// Kept for clients that predate regions
+			http.HandleFunc("/" + forecastGroup.CommonName + ".json", serveForecast(config.DefaultRegion, forecastGroup.CommonName))
+		}
+	}
 
-				if hour == "" {
-					http.Error(w, "Hour is required", http.StatusBadRequest)
+	// Expose the run every hour of every param comes from, along with the latest run published
+	http.HandleFunc("/runs.json", func(w http.ResponseWriter, r *http.Request) {
+		type paramRuns struct {
+			LatestRun string            `json:"latest_run"`
+			Hours     map[string]string `json:"hours"`
+		}
+
+		runs := make(map[string]paramRuns)
+		for _, forecastPackage := range config.Packages {
+			for _, forecastGroup := range forecastPackage.Forecasts {
+				hourRuns, err := storage.HourRuns(forecastGroup.CommonName)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
 
-				// Add a leading zero since filenames are like 00, 01, 02, etc.
-				if len(hour) == 1 {
-					hour = "0" + hour
-				}
+				runs[forecastGroup.CommonName] = paramRuns{LatestRun: latestRun(slices.Collect(maps.Values(hourRuns))), Hours: hourRuns}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		json.NewEncoder(w).Encode(runs)
+	})
+
+	// Lists what a downloaded GRIB file contains, only when a token protects it
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		http.HandleFunc("/admin/inventory", serveInventory(config, token))
+	}
+
+	// Expose download failures so that a stuck run is visible without reading the logs
+	http.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
 
-				w.Header().Set("Content-Type", "application/json"	)
-				w.Header().Set("Content-Encoding", "gzip")
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-				w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS, HEAD")
+		json.NewEncoder(w).Encode(currentStatus.Snapshot())
+	})
+}
 
-				w.WriteHeader(http.StatusOK)
+func serveForecast(region string, commonName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get the hour from the request
+		hour := r.URL.Query().Get("hour")
 
-				http.ServeFile(w, r, "storage/" + forecastGroup.CommonName + "_" + hour + ".json.gz")
-			})
+		if hour == "" {
+			http.Error(w, "Hour is required", http.StatusBadRequest)
+			return
 		}
-	}	
-}
\ No newline at end of file
+
+		hour, err := normalizeHour(hour)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json"	)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS, HEAD")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Forecast-Run")
+
+		// Tell which run the hour comes from, it can differ between hours while a run is rolled out progressively
+		if hourRuns, err := storage.HourRuns(commonName); err == nil && hourRuns[hour] != "" {
+			w.Header().Set("X-Forecast-Run", hourRuns[hour])
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		http.ServeFile(w, r, storage.ForecastFile(region, commonName, hour))
+	}
+}
+
+// normalizeHour checks an hour given to the API, it ends up in a file path
+func normalizeHour(hour string) (string, error) {
+	if _, err := strconv.Atoi(hour); err != nil {
+		return "", errors.New("Hour must be a number")
+	}
+
+	// Add a leading zero since filenames are like 00, 01, 02, etc.
+	if len(hour) == 1 {
+		hour = "0" + hour
+	}
+
+	return hour, nil
+}
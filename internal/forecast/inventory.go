@@ -0,0 +1,93 @@
+package forecast
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/grib"
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/storage"
+)
+
+// Inventory lists the messages of the GRIB file of an hour of a run
+type Inventory struct {
+	Package  string                `json:"package"`
+	Run      string                `json:"run"`
+	Hour     string                `json:"hour"`
+	Messages []grib.InventoryEntry `json:"messages"`
+}
+
+// serveInventory answers /admin/inventory?package=SP1&hour=1&run=2025-06-19T06:00:00Z with the inventory
+// of a downloaded file, the latest run downloaded is used when run is left out. Once a run is rolled out
+// only its files are kept, until the next run is. Requests must come with an "Authorization: Bearer <ADMIN_TOKEN>" header.
+func serveInventory(config *Config, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+
+		// The package, hour and run end up in a file path
+		packageName := query.Get("package")
+		if !config.hasPackage(packageName) {
+			http.Error(w, "Unknown package", http.StatusBadRequest)
+			return
+		}
+
+		hour, err := normalizeHour(query.Get("hour"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		run := query.Get("run")
+		if run != "" {
+			if _, err := time.Parse("2006-01-02T15:04:05Z", run); err != nil {
+				http.Error(w, "Run must be like 2025-06-19T06:00:00Z", http.StatusBadRequest)
+				return
+			}
+		} else {
+			run = latestDownloadedRun(packageName, hour)
+		}
+
+		filename := downloadedFile(packageName, run, hour)
+		if _, err := os.Stat(filename); err != nil {
+			filename = storage.GribFile(packageName, run, hour)
+		}
+		if _, err := os.Stat(filename); run == "" || err != nil {
+			http.Error(w, "No downloaded file for this hour", http.StatusNotFound)
+			return
+		}
+
+		messages, err := grib.Inventory(filename)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		json.NewEncoder(w).Encode(Inventory{Package: packageName, Run: run, Hour: hour, Messages: messages})
+	}
+}
+
+// latestDownloadedRun returns the latest run of which the file of an hour is in tmp or kept in storage, or "" when there is none
+func latestDownloadedRun(packageName string, hour string) string {
+	files, _ := filepath.Glob(downloadedFile(packageName, "*", hour))
+	kept, _ := filepath.Glob(storage.GribFile(packageName, "*", hour))
+	files = append(files, kept...)
+
+	runs := []string{}
+	for _, file := range files {
+		run := strings.TrimPrefix(filepath.Base(file), "file_"+packageName+"_")
+		runs = append(runs, strings.TrimSuffix(run, "_"+hour+".grib2"))
+	}
+
+	return latestRun(runs)
+}
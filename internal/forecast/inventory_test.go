@@ -0,0 +1,82 @@
+package forecast
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestServeInventoryChecksRequests(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{Packages: []ForecastPackage{{Package: "SP1"}}}
+	handler := serveInventory(config, "secret")
+
+	tests := []struct {
+		name          string
+		query         string
+		authorization string
+		want          int
+	}{
+		{"no token", "?package=SP1&hour=1", "", http.StatusUnauthorized},
+		{"wrong token", "?package=SP1&hour=1", "Bearer guess", http.StatusUnauthorized},
+		{"unknown package", "?package=../SP1&hour=1", "Bearer secret", http.StatusBadRequest},
+		{"invalid hour", "?package=SP1&hour=1/..", "Bearer secret", http.StatusBadRequest},
+		{"invalid run", "?package=SP1&hour=1&run=../../etc", "Bearer secret", http.StatusBadRequest},
+		{"no downloaded file", "?package=SP1&hour=1", "Bearer secret", http.StatusNotFound},
+		{"run not downloaded", "?package=SP1&hour=1&run=2025-06-19T06:00:00Z", "Bearer secret", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/admin/inventory"+tt.query, nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler(recorder, request)
+
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d; want %d (%s)", recorder.Code, tt.want, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestLatestDownloadedRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"tmp", "storage/grib"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, file := range []string{
+		"storage/grib/file_SP1_2025-06-19T00:00:00Z_01.grib2",
+		"storage/grib/file_SP1_2025-06-19T00:00:00Z_03.grib2",
+		"tmp/file_SP1_2025-06-19T03:00:00Z_01.grib2",
+		"tmp/file_SP1_2025-06-19T06:00:00Z_01.grib2",
+		"tmp/file_SP1_2025-06-19T09:00:00Z_01.grib2.part",
+		"tmp/file_SP1_2025-06-19T09:00:00Z_02.grib2",
+		"tmp/file_SP2_2025-06-19T09:00:00Z_01.grib2",
+	} {
+		if err := os.WriteFile(file, []byte("GRIB"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if run := latestDownloadedRun("SP1", "01"); run != "2025-06-19T06:00:00Z" {
+		t.Errorf("latestDownloadedRun(SP1, 01) = %q; want 2025-06-19T06:00:00Z", run)
+	}
+	if run := latestDownloadedRun("SP1", "03"); run != "2025-06-19T00:00:00Z" {
+		t.Errorf("latestDownloadedRun(SP1, 03) = %q; want the rolled out 2025-06-19T00:00:00Z", run)
+	}
+	if run := latestDownloadedRun("SP1", "04"); run != "" {
+		t.Errorf("latestDownloadedRun(SP1, 04) = %q; want none", run)
+	}
+}
@@ -0,0 +1,72 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+// RetryPolicy tells how often a failing download is retried, and after how many
+// failed checks a run is abandoned until a newer one gets published.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	GiveUpAfter int
+}
+
+func retryPolicyFromEnv() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: utils.GetEnvInt("DOWNLOAD_MAX_ATTEMPTS", 4),
+		BaseDelay:   utils.GetEnvDuration("DOWNLOAD_RETRY_BASE_DELAY", 5*time.Second),
+		MaxDelay:    utils.GetEnvDuration("DOWNLOAD_RETRY_MAX_DELAY", 2*time.Minute),
+		GiveUpAfter: utils.GetEnvInt("RUN_FAILURE_THRESHOLD", 3),
+	}
+}
+
+// Delay returns the time to wait before the given retry (starting at 1).
+// It doubles on every attempt up to MaxDelay, and half of it is random so that
+// both packages don't hammer the server at the same time.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	// Shifting by bits.Len64(MaxDelay/BaseDelay) or more would reach MaxDelay, or overflow
+	delay := p.MaxDelay
+	if p.BaseDelay <= 0 {
+		delay = 0
+	} else if retry-1 < bits.Len64(uint64(p.MaxDelay/p.BaseDelay)) {
+		delay = p.BaseDelay << (retry - 1)
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Do calls fn until it succeeds, MaxAttempts is reached or ctx is cancelled, and returns the last error
+func (p RetryPolicy) Do(ctx context.Context, clock Clock, description string, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= max(p.MaxAttempts, 1); attempt++ {
+		if attempt > 1 {
+			delay := p.Delay(attempt - 1)
+			utils.Log(fmt.Sprintf("Retrying %s in %s (attempt %d/%d) after error: %s", description, delay, attempt, p.MaxAttempts, err.Error()))
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-clock.After(delay):
+			}
+		}
+
+		err = fn()
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,97 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	testCases := []struct {
+		retry int
+		min   time.Duration
+		max   time.Duration
+	}{
+		{retry: 1, min: 500 * time.Millisecond, max: time.Second},
+		{retry: 2, min: time.Second, max: 2 * time.Second},
+		{retry: 3, min: 2 * time.Second, max: 4 * time.Second},
+		{retry: 5, min: 5 * time.Second, max: 10 * time.Second},
+		{retry: 100, min: 5 * time.Second, max: 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < 20; i++ {
+			delay := policy.Delay(tc.retry)
+			if delay < tc.min || delay > tc.max {
+				t.Errorf("Delay(%d) = %s; want between %s and %s", tc.retry, delay, tc.min, tc.max)
+			}
+		}
+	}
+
+	// 10s shifted by 30 overflows, the delay must stay at MaxDelay
+	long := RetryPolicy{BaseDelay: 10 * time.Second, MaxDelay: 2 * time.Minute}
+	for _, retry := range []int{5, 31, 40, 100} {
+		if delay := long.Delay(retry); delay < time.Minute || delay > 2*time.Minute {
+			t.Errorf("Delay(%d) with a base of 10s = %s; want between 1m and 2m", retry, delay)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	calls := 0
+	err := policy.Do(context.Background(), systemClock{}, "failing download", func() error {
+		calls++
+		return errors.New("connection reset")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("Do() = %v after %d calls; want an error after 3 calls", err, calls)
+	}
+
+	calls = 0
+	err = policy.Do(context.Background(), systemClock{}, "flaky download", func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Do() = %v after %d calls; want no error after 2 calls", err, calls)
+	}
+}
+
+func TestStatusGivesUpAfterThreshold(t *testing.T) {
+	status := newStatus()
+	err := errors.New("file not found")
+	now := time.Date(2025, 6, 19, 10, 0, 0, 0, time.UTC)
+
+	if status.RecordFailure("SP1", "2025-06-19T06:00:00Z", "12", 4, err, 3, now) {
+		t.Errorf("run given up after the first failed check; want it after the third")
+	}
+	if status.RecordFailure("SP1", "2025-06-19T06:00:00Z", "13", 4, err, 3, now) {
+		t.Errorf("run given up after the second failed check; want it after the third")
+	}
+	// The same hour failing again is one more failed check
+	if !status.RecordFailure("SP1", "2025-06-19T06:00:00Z", "12", 4, err, 3, now) {
+		t.Errorf("run not given up after the third failed check")
+	}
+	if snapshot := status.Snapshot()["SP1"]; snapshot.FailedHours != 2 || snapshot.FailedChecks != 3 {
+		t.Errorf("status = %d failed hours and %d failed checks; want 2 and 3", snapshot.FailedHours, snapshot.FailedChecks)
+	}
+	if !status.IsGivenUp("SP1", "2025-06-19T06:00:00Z") {
+		t.Errorf("IsGivenUp(SP1, 06:00) = false; want true")
+	}
+
+	// A newer run starts from a clean slate
+	if status.RecordFailure("SP1", "2025-06-19T09:00:00Z", "01", 4, err, 3, now) {
+		t.Errorf("newer run given up after its first failure")
+	}
+	if failure := status.Snapshot()["SP1"].Failures["01"]; failure == nil || failure.Attempts != 4 || !failure.LastAttempt.Equal(now) {
+		t.Errorf("failure of hour 01 = %+v; want 4 attempts at %s", failure, now)
+	}
+}
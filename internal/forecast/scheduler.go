@@ -0,0 +1,281 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/grib"
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/storage"
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+const (
+	DEFAULT_POLL_INTERVAL = 60 * time.Second
+	// A corrupt GRIB file is downloaded again once before the hour is counted as failed
+	CORRUPT_FILE_ATTEMPTS = 2
+)
+
+// Scheduler polls every package for new runs at its own pace until its context is cancelled
+type Scheduler struct {
+	Source    Source
+	Discovery *RunDiscovery
+	Clock     Clock
+	Policy    RetryPolicy
+	Status    *Status
+	Pool      *WorkerPool
+	Packages  []ForecastPackage
+	Regions   []Region
+	// PollIntervals overrides DefaultPollInterval for some packages
+	PollIntervals       map[string]time.Duration
+	DefaultPollInterval time.Duration
+	// Progressive publishes every hour as soon as it is processed instead of waiting for the whole run
+	Progressive bool
+}
+
+// NewScheduler returns a scheduler configured from the environment. The poll interval is read
+// from POLL_INTERVAL and can be overridden for a single package with POLL_INTERVAL_SP1 for instance.
+func NewScheduler(source Source, config *Config) *Scheduler {
+	pollIntervals := make(map[string]time.Duration)
+	for _, forecastPackage := range config.Packages {
+		key := "POLL_INTERVAL_" + strings.ToUpper(forecastPackage.Package)
+		if interval := utils.GetEnvDuration(key, 0); interval > 0 {
+			pollIntervals[forecastPackage.Package] = interval
+		}
+	}
+
+	clock := systemClock{}
+
+	return &Scheduler{
+		Source:              source,
+		Discovery:           newRunDiscoveryFromEnv(source, clock),
+		Clock:               clock,
+		Policy:              retryPolicyFromEnv(),
+		Status:              currentStatus,
+		Pool:                workerPoolFromEnv(),
+		Packages:            config.Packages,
+		Regions:             config.Regions,
+		PollIntervals:       pollIntervals,
+		DefaultPollInterval: utils.GetEnvDuration("POLL_INTERVAL", DEFAULT_POLL_INTERVAL),
+		Progressive:         os.Getenv("ROLLOUT_MODE") == "progressive",
+	}
+}
+
+// StartFetching polls data.gouv.fr (or the configured source) until ctx is cancelled
+func StartFetching(ctx context.Context, source Source, config *Config) {
+	NewScheduler(source, config).Run(ctx)
+}
+
+// Run processes every package in its own goroutine and returns once they all stopped,
+// which happens when ctx is cancelled and the hour being processed is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	for _, forecastPackage := range s.Packages {
+		wg.Add(1)
+		go func(fp ForecastPackage) {
+			defer wg.Done()
+			s.runPackage(ctx, fp)
+		}(forecastPackage)
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (s *Scheduler) pollInterval(packageName string) time.Duration {
+	if interval, exists := s.PollIntervals[packageName]; exists {
+		return interval
+	}
+
+	return s.DefaultPollInterval
+}
+
+func (s *Scheduler) runPackage(ctx context.Context, forecastPackage ForecastPackage) {
+	for {
+		s.processForecastPackage(ctx, forecastPackage)
+
+		interval := s.pollInterval(forecastPackage.Package)
+		s.Status.SetNextCheck(forecastPackage.Package, s.Clock.Now().Add(interval))
+
+		select {
+		case <-ctx.Done():
+			utils.Log("Stopped fetching package " + forecastPackage.Package)
+			return
+		case <-s.Clock.After(interval):
+		}
+	}
+}
+
+// Package is like SP1 or SP2 from méteo-france
+// Each package is stored in a separate folder in data.gouv.fr
+// So we download every hour of every package
+func (s *Scheduler) processForecastPackage(ctx context.Context, forecastPackage ForecastPackage) {
+	run := s.Discovery.LatestCompleteRun(ctx, forecastPackage.Package)
+
+	if run == "" {
+		utils.Log("No complete run found for package " + forecastPackage.Package)
+		return
+	}
+
+	regionNames := make([]string, len(s.Regions))
+	for i, region := range s.Regions {
+		regionNames[i] = region.Name
+	}
+
+	if storage.IsUpToDate(forecastPackage.Package, run, regionNames) {
+		utils.Log("Forecast already downloaded, skipping " + run)
+		return
+	}
+
+	if s.Status.IsGivenUp(forecastPackage.Package, run) {
+		utils.Log("Run " + run + " of package " + forecastPackage.Package + " was given up, waiting for a newer one")
+		return
+	}
+
+	utils.Log("Forecast found for package " + forecastPackage.Package + " run: " + run)
+
+	// Extract common names from forecast groups
+	commonNames := make([]string, len(forecastPackage.Forecasts))
+	for i, fg := range forecastPackage.Forecasts {
+		commonNames[i] = fg.CommonName
+	}
+
+	// Process every hour from 1 to 51, the pool decides how many are downloaded and decoded at the same time
+	hoursCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var failOnce sync.Once
+	failed := false
+	history := newAccumulations()
+
+	// Optional groups skipped by each hour, they are left out when the run is rolled out
+	var skippedMutex sync.Mutex
+	skipped := make(map[string][]string)
+
+	for _, hour := range getAvailableHours() {
+		wg.Add(1)
+		go func(hour string) {
+			defer wg.Done()
+
+			attempts, skippedNames, err := s.processHour(hoursCtx, forecastPackage, run, hour, regionNames, history)
+			if err == nil {
+				skippedMutex.Lock()
+				skipped[hour] = skippedNames
+				skippedMutex.Unlock()
+			}
+			if err == nil || hoursCtx.Err() != nil {
+				return
+			}
+
+			// Stop the other hours, the run will be processed again on the next check
+			failOnce.Do(func() {
+				failed = true
+				cancel()
+
+				utils.Log("Error getting single forecast: " + err.Error())
+				if s.Status.RecordFailure(forecastPackage.Package, run, hour, attempts, err, s.Policy.GiveUpAfter, s.Clock.Now()) {
+					utils.Log(fmt.Sprintf("Giving up run %s of package %s after %d failed checks", run, forecastPackage.Package, s.Policy.GiveUpAfter))
+				}
+			})
+		}(hour)
+	}
+
+	wg.Wait()
+
+	if failed || ctx.Err() != nil {
+		return
+	}
+
+	if s.Progressive {
+		storage.FinishRollOut(forecastPackage.Package, run)
+	} else {
+		storage.RollOut(forecastPackage.Package, regionNames, commonNames, run, getAvailableHours(), skipped)
+	}
+}
+
+// processHour downloads and decodes a single hour of a run, and returns how many downloads it attempted
+// along with the optional groups that were skipped
+func (s *Scheduler) processHour(ctx context.Context, forecastPackage ForecastPackage, run string, hour string, regionNames []string, history *accumulations) (int, []string, error) {
+	var windows map[string][]string
+	var skipped []string
+	var err error
+	attempts := 0
+	for attempt := 1; attempt <= CORRUPT_FILE_ATTEMPTS; attempt++ {
+		var filename string
+		var downloads int
+		filename, downloads, err = s.downloadHour(ctx, forecastPackage.Package, run, hour)
+		attempts += downloads
+		if err != nil {
+			return attempts, nil, err
+		}
+
+		utils.Log("Forecast retrieved for " + run + " " + hour)
+
+		if ctx.Err() != nil {
+			return attempts, nil, ctx.Err()
+		}
+
+		// Now we process each param (temperature, humidity) of a given package
+		s.Pool.Decode(func() {
+			windows, skipped, err = processForecastGroup(filename, forecastPackage, s.Regions, run, hour, history)
+		})
+
+		if !errors.Is(err, grib.ErrCorruptMessage) {
+			break
+		}
+
+		// The file was damaged on the way or on disk, a fresh download may fix it
+		utils.Log(fmt.Sprintf("Corrupt GRIB file for %s %s %s (attempt %d/%d): %s", forecastPackage.Package, run, hour, attempt, CORRUPT_FILE_ATTEMPTS, err.Error()))
+		os.Remove(filename)
+	}
+	if err != nil {
+		return attempts, nil, err
+	}
+
+	s.Status.RecordSuccess(forecastPackage.Package, run, hour)
+
+	if s.Progressive {
+		// Groups with a window are published once both ends of their window are processed
+		hourlyNames := []string{}
+		for _, forecastGroup := range forecastPackage.Forecasts {
+			if forecastGroup.Window == 0 && !slices.Contains(skipped, forecastGroup.CommonName) {
+				hourlyNames = append(hourlyNames, forecastGroup.CommonName)
+			}
+		}
+		storage.PublishHour(regionNames, hourlyNames, run, hour)
+		storage.WithdrawHour(regionNames, skipped, hour)
+
+		for commonName, hours := range windows {
+			for _, windowHour := range hours {
+				storage.PublishHour(regionNames, []string{commonName}, run, windowHour)
+			}
+		}
+	}
+
+	return attempts, skipped, nil
+}
+
+// downloadHour downloads the GRIB file of an hour, retrying with the policy of the scheduler,
+// and returns how many attempts were made
+func (s *Scheduler) downloadHour(ctx context.Context, packageName string, run string, hour string) (string, int, error) {
+	var filename string
+	attempts := 0
+	err := s.Policy.Do(ctx, s.Clock, packageName+" "+run+" "+hour, func() error {
+		attempts++
+		return s.Pool.Download(ctx, func() error {
+			var err error
+			filename, err = downloadPackage(ctx, s.Source, packageName, run, hour)
+			return err
+		})
+	})
+
+	return filename, attempts, err
+}
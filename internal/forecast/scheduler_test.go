@@ -0,0 +1,201 @@
+package forecast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/grib"
+)
+
+// fakeClock never advances by itself, waits are reported on the waits channel
+type fakeClock struct {
+	now   time.Time
+	waits chan time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return make(chan time.Time)
+}
+
+func TestSchedulerStopsWhenCancelled(t *testing.T) {
+	clock := &fakeClock{
+		now:   time.Date(2025, 6, 19, 10, 0, 0, 0, time.UTC),
+		waits: make(chan time.Duration, 1),
+	}
+	status := newStatus()
+	source := &DirSource{Dir: t.TempDir()}
+
+	scheduler := &Scheduler{
+		Source:              source,
+		Discovery:           &RunDiscovery{Source: source, Clock: clock},
+		Clock:               clock,
+		Status:              status,
+		Packages:            []ForecastPackage{{Package: "SP1"}},
+		PollIntervals:       map[string]time.Duration{"SP1": 5 * time.Minute},
+		DefaultPollInterval: time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- scheduler.Run(ctx)
+	}()
+
+	if wait := <-clock.waits; wait != 5*time.Minute {
+		t.Errorf("waited %s between two polls; want 5m", wait)
+	}
+
+	expectedNextCheck := clock.now.Add(5 * time.Minute)
+	if nextCheck := status.Snapshot()["SP1"].NextCheck; !nextCheck.Equal(expectedNextCheck) {
+		t.Errorf("next check = %s; want %s", nextCheck, expectedNextCheck)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Run() = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after its context was cancelled")
+	}
+}
+
+func TestProcessHourDownloadsCorruptFileAgain(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A GRIB message announcing 1000 bytes but cut after a few of them
+	content := []byte("GRIB\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x03\xe8truncated")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeContent(w, r, "file.grib2", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	scheduler := &Scheduler{
+		Source: &HTTPSource{BaseURL: server.URL, Client: server.Client()},
+		Clock:  systemClock{},
+		Policy: RetryPolicy{MaxAttempts: 1},
+		Status: newStatus(),
+		Pool:   NewWorkerPool(1, 1),
+	}
+	forecastPackage := ForecastPackage{
+		Package:   "SP1",
+		Forecasts: []ForecastGroup{{CommonName: "temperature", Fields: []grib.Selector{{ShortName: "2t"}}, Handler: "default"}},
+	}
+
+	attempts, _, err := scheduler.processHour(context.Background(), forecastPackage, "2025-06-19T06:00:00Z", "01", nil, newAccumulations())
+	if !errors.Is(err, grib.ErrCorruptMessage) {
+		t.Errorf("processHour() = %v; want %v", err, grib.ErrCorruptMessage)
+	}
+	if attempts != CORRUPT_FILE_ATTEMPTS {
+		t.Errorf("processHour() made %d attempts; want %d", attempts, CORRUPT_FILE_ATTEMPTS)
+	}
+	if requests != CORRUPT_FILE_ATTEMPTS {
+		t.Errorf("file downloaded %d times; want %d", requests, CORRUPT_FILE_ATTEMPTS)
+	}
+	if _, err := os.Stat("./tmp/file_SP1_2025-06-19T06:00:00Z_01.grib2"); !os.IsNotExist(err) {
+		t.Errorf("corrupt file was kept")
+	}
+}
+
+// openCountingSource counts how many times the file of every hour is opened
+type openCountingSource struct {
+	*DirSource
+	mu    sync.Mutex
+	opens map[string]int
+}
+
+func (s *openCountingSource) Open(ctx context.Context, packageName string, run string, hour string, offset int64) (io.ReadCloser, int64, error) {
+	s.mu.Lock()
+	s.opens[hour]++
+	s.mu.Unlock()
+
+	return s.DirSource.Open(ctx, packageName, run, hour, offset)
+}
+
+func TestSchedulerGivesUpRunWithOneCorruptHour(t *testing.T) {
+	dir := t.TempDir()
+	run := "2025-06-19T06:00:00Z"
+	for _, hour := range getAvailableHours() {
+		path := filepath.Join(dir, filepath.FromSlash(gribPath("SP1", run, hour)))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		// Hours without any message are processed fine when the package has no forecast, hour 12 is cut
+		content := []byte{}
+		if hour == "12" {
+			content = []byte("GRIB\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x03\xe8truncated")
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"tmp", "storage"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := &openCountingSource{DirSource: &DirSource{Dir: dir}, opens: make(map[string]int)}
+	status := newStatus()
+	scheduler := &Scheduler{
+		Source:    source,
+		Discovery: &RunDiscovery{Source: source, Clock: systemClock{}, Concurrency: 4},
+		Clock:     systemClock{},
+		Policy:    RetryPolicy{MaxAttempts: 1, GiveUpAfter: 3},
+		Status:    status,
+		Pool:      NewWorkerPool(4, 4),
+		Packages:  []ForecastPackage{{Package: "SP1"}},
+	}
+
+	for check := 1; check <= 3; check++ {
+		if status.IsGivenUp("SP1", run) {
+			t.Fatalf("run given up before check %d", check)
+		}
+		scheduler.processForecastPackage(context.Background(), scheduler.Packages[0])
+	}
+
+	snapshot := status.Snapshot()["SP1"]
+	if !snapshot.GivenUp || snapshot.FailedChecks != 3 || snapshot.Failures["12"] == nil {
+		t.Errorf("status = %+v; want run given up after 3 failed checks of hour 12", snapshot)
+	}
+
+	// Complete files are kept in tmp between checks, only the corrupt hour is downloaded again
+	for hour, opens := range source.opens {
+		if hour != "12" && opens > 1 {
+			t.Errorf("hour %s downloaded %d times; want once", hour, opens)
+		}
+	}
+	if opens := source.opens["12"]; opens != 3*CORRUPT_FILE_ATTEMPTS {
+		t.Errorf("hour 12 downloaded %d times; want %d", opens, 3*CORRUPT_FILE_ATTEMPTS)
+	}
+
+	// A given up run isn't processed anymore
+	scheduler.processForecastPackage(context.Background(), scheduler.Packages[0])
+	if opens := source.opens["12"]; opens != 3*CORRUPT_FILE_ATTEMPTS {
+		t.Errorf("hour 12 downloaded again after the run was given up")
+	}
+}
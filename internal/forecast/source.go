@@ -0,0 +1,199 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+const (
+	DEFAULT_BASE_URL = "https://object.files.data.gouv.fr/meteofrance-pnt/pnt"
+)
+
+// Source gives access to the AROME GRIB files of every package, run and hour,
+// wherever they are hosted (data.gouv.fr, a mirror or a local directory).
+type Source interface {
+	// ListRuns returns the candidate run datetimes for a package, most recent first.
+	ListRuns(ctx context.Context, packageName string) ([]string, error)
+	// IsAvailable tells whether the file of a given hour of a run has been published.
+	IsAvailable(ctx context.Context, packageName string, run string, hour string) bool
+	// Open returns the content of the GRIB file of a given hour of a run starting at offset,
+	// along with the total size of the file, or -1 when it is unknown.
+	Open(ctx context.Context, packageName string, run string, hour string, offset int64) (io.ReadCloser, int64, error)
+}
+
+// NewSourceFromEnv returns a local directory source when FORECAST_SOURCE_DIR is set,
+// and an HTTP source pointing at FORECAST_BASE_URL (or data.gouv.fr) otherwise.
+func NewSourceFromEnv() Source {
+	if dir := os.Getenv("FORECAST_SOURCE_DIR"); dir != "" {
+		return &DirSource{Dir: dir}
+	}
+
+	baseURL := os.Getenv("FORECAST_BASE_URL")
+	if baseURL == "" {
+		baseURL = DEFAULT_BASE_URL
+	}
+
+	return &HTTPSource{BaseURL: baseURL, Client: http.DefaultClient, Calendar: runCalendarFromEnv(systemClock{})}
+}
+
+// gribPath is the path of a GRIB file relative to the root of the source,
+// it follows the layout of data.gouv.fr so that a mirror can be used as is.
+func gribPath(packageName string, run string, hour string) string {
+	return fmt.Sprintf("%s/arome/001/%s/arome__001__%s__%sH__%s.grib2", run, packageName, packageName, hour, run)
+}
+
+// HTTPSource fetches GRIB files from data.gouv.fr or any server exposing the same layout
+type HTTPSource struct {
+	BaseURL  string
+	Client   *http.Client
+	Calendar RunCalendar
+}
+
+func (s *HTTPSource) url(packageName string, run string, hour string) string {
+	return s.BaseURL + "/" + gribPath(packageName, run, hour)
+}
+
+// ListRuns cannot list the bucket, so it returns the runs that should have been published by now
+func (s *HTTPSource) ListRuns(ctx context.Context, packageName string) ([]string, error) {
+	return s.Calendar.Runs(), nil
+}
+
+func (s *HTTPSource) IsAvailable(ctx context.Context, packageName string, run string, hour string) bool {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, s.url(packageName, run, hour), nil)
+	if err != nil {
+		return false
+	}
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+
+	return response.StatusCode == http.StatusOK
+}
+
+// Open uses a Range request to resume from offset. Servers ignoring the Range header are
+// handled by skipping the first offset bytes of the response.
+func (s *HTTPSource) Open(ctx context.Context, packageName string, run string, hour string, offset int64) (io.ReadCloser, int64, error) {
+	url := s.url(packageName, run, hour)
+	utils.Log(fmt.Sprintf("Downloading %s from byte %d", url, offset))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		return response.Body, contentRangeTotal(response.Header.Get("Content-Range")), nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file already holds everything there is to download
+		response.Body.Close()
+		return io.NopCloser(strings.NewReader("")), contentRangeTotal(response.Header.Get("Content-Range")), nil
+	case http.StatusOK:
+		if offset > 0 {
+			if _, err := io.CopyN(io.Discard, response.Body, offset); err != nil {
+				response.Body.Close()
+				return nil, -1, err
+			}
+		}
+		return response.Body, response.ContentLength, nil
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, -1, fmt.Errorf("file not found: %s", url)
+	default:
+		response.Body.Close()
+		return nil, -1, fmt.Errorf("unexpected status %d for %s", response.StatusCode, url)
+	}
+}
+
+// contentRangeTotal extracts the complete length from a header like "bytes 100-199/200" or "bytes */200"
+func contentRangeTotal(contentRange string) int64 {
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return -1
+	}
+
+	total, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return total
+}
+
+// DirSource reads GRIB files from a local directory laid out like data.gouv.fr,
+// e.g. a mirror or files downloaded beforehand on a machine without internet access.
+type DirSource struct {
+	Dir string
+}
+
+func (s *DirSource) path(packageName string, run string, hour string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(gribPath(packageName, run, hour)))
+}
+
+// ListRuns returns every run folder containing the package
+func (s *DirSource) ListRuns(ctx context.Context, packageName string) ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(s.Dir, entry.Name(), "arome", "001", packageName))
+		if err == nil && info.IsDir() {
+			runs = append(runs, entry.Name())
+		}
+	}
+
+	sortRunsNewestFirst(runs)
+
+	return runs, nil
+}
+
+func (s *DirSource) IsAvailable(ctx context.Context, packageName string, run string, hour string) bool {
+	info, err := os.Stat(s.path(packageName, run, hour))
+	return err == nil && info.Mode().IsRegular()
+}
+
+func (s *DirSource) Open(ctx context.Context, packageName string, run string, hour string, offset int64) (io.ReadCloser, int64, error) {
+	file, err := os.Open(s.path(packageName, run, hour))
+	if err != nil {
+		return nil, -1, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, -1, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, -1, err
+	}
+
+	return file, info.Size(), nil
+}
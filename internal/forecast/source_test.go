@@ -0,0 +1,60 @@
+package forecast
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		gribPath("SP1", "2025-06-19T03:00:00Z", "01"): "older run",
+		gribPath("SP1", "2025-06-19T06:00:00Z", "01"): "latest run",
+		gribPath("SP2", "2025-06-19T09:00:00Z", "01"): "other package",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := &DirSource{Dir: dir}
+
+	runs, err := source.ListRuns(context.Background(), "SP1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRuns := []string{"2025-06-19T06:00:00Z", "2025-06-19T03:00:00Z"}
+	if len(runs) != len(expectedRuns) || runs[0] != expectedRuns[0] || runs[1] != expectedRuns[1] {
+		t.Errorf("ListRuns(SP1) = %v; want %v", runs, expectedRuns)
+	}
+
+	if !source.IsAvailable(context.Background(), "SP1", "2025-06-19T06:00:00Z", "01") {
+		t.Errorf("IsAvailable(SP1, 06:00, 01) = false; want true")
+	}
+	if source.IsAvailable(context.Background(), "SP1", "2025-06-19T06:00:00Z", "02") {
+		t.Errorf("IsAvailable(SP1, 06:00, 02) = true; want false")
+	}
+
+	reader, size, err := source.Open(context.Background(), "SP1", "2025-06-19T06:00:00Z", "01", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "run" || size != 10 {
+		t.Errorf("Open(SP1, 06:00, 01, 7) = %q, %d; want %q, %d", content, size, "run", 10)
+	}
+}
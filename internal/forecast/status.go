@@ -0,0 +1,135 @@
+package forecast
+
+import (
+	"sync"
+	"time"
+)
+
+// HourFailure describes an hour of a run that could not be downloaded, even after retrying
+type HourFailure struct {
+	Hour        string    `json:"hour"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	LastAttempt time.Time `json:"last_attempt"`
+}
+
+// PackageStatus is what the fetcher knows about the run being processed for a package
+type PackageStatus struct {
+	Run          string                  `json:"run"`
+	Failures     map[string]*HourFailure `json:"failures"`
+	FailedHours  int                     `json:"failed_hours"`
+	FailedChecks int                     `json:"failed_checks"`
+	GivenUp      bool                    `json:"given_up"`
+	NextCheck    time.Time               `json:"next_check"`
+}
+
+// Status keeps track of the fetching of every package so that it can be exposed through the API
+type Status struct {
+	mu       sync.Mutex
+	packages map[string]*PackageStatus
+}
+
+var currentStatus = newStatus()
+
+func newStatus() *Status {
+	return &Status{packages: make(map[string]*PackageStatus)}
+}
+
+// packageStatus returns the status of a package for the given run, failures of previous runs are forgotten.
+// Callers must hold the lock.
+func (s *Status) packageStatus(packageName string, run string) *PackageStatus {
+	status, exists := s.packages[packageName]
+	if !exists || status.Run != run {
+		previous := status
+		status = &PackageStatus{Run: run, Failures: make(map[string]*HourFailure)}
+		if exists {
+			status.NextCheck = previous.NextCheck
+		}
+		s.packages[packageName] = status
+	}
+
+	return status
+}
+
+// SetNextCheck tells when the scheduler will look for a new run of a package
+func (s *Status) SetNextCheck(packageName string, nextCheck time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, exists := s.packages[packageName]
+	if !exists {
+		status = s.packageStatus(packageName, "")
+	}
+	status.NextCheck = nextCheck
+}
+
+// RecordFailure stores the failure of an hour and tells whether the run should be given up, which happens
+// once giveUpAfter checks of the same run failed. A check stops at its first failed hour, so it is called
+// once per failed check and an hour failing on every check is enough to give up.
+func (s *Status) RecordFailure(packageName string, run string, hour string, attempts int, err error, giveUpAfter int, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.packageStatus(packageName, run)
+
+	failure, exists := status.Failures[hour]
+	if !exists {
+		failure = &HourFailure{Hour: hour}
+		status.Failures[hour] = failure
+	}
+	failure.Attempts += attempts
+	failure.LastError = err.Error()
+	failure.LastAttempt = now
+
+	status.FailedHours = len(status.Failures)
+	status.FailedChecks++
+	if status.FailedChecks >= giveUpAfter {
+		status.GivenUp = true
+	}
+
+	return status.GivenUp
+}
+
+// RecordSuccess forgets previous failures of an hour once it has been downloaded
+func (s *Status) RecordSuccess(packageName string, run string, hour string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.packageStatus(packageName, run)
+	delete(status.Failures, hour)
+	status.FailedHours = len(status.Failures)
+}
+
+func (s *Status) IsGivenUp(packageName string, run string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, exists := s.packages[packageName]
+	return exists && status.Run == run && status.GivenUp
+}
+
+// Snapshot returns a copy of the status of every package that is safe to serialize
+func (s *Status) Snapshot() map[string]PackageStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]PackageStatus, len(s.packages))
+	for packageName, status := range s.packages {
+		failures := make(map[string]*HourFailure, len(status.Failures))
+		for hour, failure := range status.Failures {
+			copied := *failure
+			failures[hour] = &copied
+		}
+
+		snapshot[packageName] = PackageStatus{
+			Run:          status.Run,
+			Failures:     failures,
+			FailedHours:  status.FailedHours,
+			FailedChecks: status.FailedChecks,
+			GivenUp:      status.GivenUp,
+			NextCheck:    status.NextCheck,
+		}
+	}
+
+	return snapshot
+}
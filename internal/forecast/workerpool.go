@@ -0,0 +1,50 @@
+package forecast
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
+)
+
+// WorkerPool bounds the number of downloads and of eccodes decodings running at the same time.
+// It is shared by all packages, so the limits apply to the whole process.
+type WorkerPool struct {
+	downloads chan struct{}
+	decodes   chan struct{}
+}
+
+func NewWorkerPool(downloads int, decodes int) *WorkerPool {
+	return &WorkerPool{
+		downloads: make(chan struct{}, max(downloads, 1)),
+		decodes:   make(chan struct{}, max(decodes, 1)),
+	}
+}
+
+// workerPoolFromEnv reads DOWNLOAD_WORKERS (network bound) and DECODE_WORKERS (CPU bound)
+func workerPoolFromEnv() *WorkerPool {
+	return NewWorkerPool(
+		utils.GetEnvInt("DOWNLOAD_WORKERS", 4),
+		utils.GetEnvInt("DECODE_WORKERS", runtime.NumCPU()),
+	)
+}
+
+// Download runs fn once a download slot is free, unless ctx is cancelled first
+func (p *WorkerPool) Download(ctx context.Context, fn func() error) error {
+	select {
+	case p.downloads <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.downloads }()
+
+	return fn()
+}
+
+// Decode runs fn once a decoding slot is free
+func (p *WorkerPool) Decode(fn func()) {
+	p.decodes <- struct{}{}
+	defer func() { <-p.decodes }()
+
+	fn()
+}
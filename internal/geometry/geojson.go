@@ -0,0 +1,139 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// geoJSON holds the members of any GeoJSON object we care about, whatever its type
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []geoJSON       `json:"geometries"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// LoadGeoJSON reads the polygons of a GeoJSON file, see ParseGeoJSON
+func LoadGeoJSON(path string) (MultiPolygon, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	area, err := ParseGeoJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return area, nil
+}
+
+// ParseGeoJSON returns every Polygon and MultiPolygon of a GeoJSON document as a single MultiPolygon.
+// The document can be a FeatureCollection, a Feature, a GeometryCollection or a geometry.
+func ParseGeoJSON(content []byte) (MultiPolygon, error) {
+	var object geoJSON
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, err
+	}
+
+	area, err := object.polygons()
+	if err != nil {
+		return nil, err
+	}
+	if len(area) == 0 {
+		return nil, fmt.Errorf("no Polygon or MultiPolygon found")
+	}
+
+	return area, nil
+}
+
+func (g *geoJSON) polygons() (MultiPolygon, error) {
+	switch g.Type {
+	case "FeatureCollection":
+		area := MultiPolygon{}
+		for i := range g.Features {
+			polygons, err := g.Features[i].polygons()
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			area = append(area, polygons...)
+		}
+		return area, nil
+
+	case "Feature":
+		if g.Geometry == nil {
+			return MultiPolygon{}, nil
+		}
+		return g.Geometry.polygons()
+
+	case "GeometryCollection":
+		area := MultiPolygon{}
+		for i := range g.Geometries {
+			polygons, err := g.Geometries[i].polygons()
+			if err != nil {
+				return nil, fmt.Errorf("geometry %d: %w", i, err)
+			}
+			area = append(area, polygons...)
+		}
+		return area, nil
+
+	case "Polygon":
+		var coordinates [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %w", err)
+		}
+		polygon, err := toPolygon(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return MultiPolygon{polygon}, nil
+
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %w", err)
+		}
+		area := MultiPolygon{}
+		for _, polygonCoordinates := range coordinates {
+			polygon, err := toPolygon(polygonCoordinates)
+			if err != nil {
+				return nil, err
+			}
+			area = append(area, polygon)
+		}
+		return area, nil
+
+	case "Point", "MultiPoint", "LineString", "MultiLineString":
+		// Nothing to filter points with
+		return MultiPolygon{}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown GeoJSON type %q", g.Type)
+	}
+}
+
+// toPolygon converts GeoJSON rings, whose positions are [lon, lat] or [lon, lat, altitude]
+func toPolygon(coordinates [][][]float64) (Polygon, error) {
+	if len(coordinates) == 0 {
+		return nil, fmt.Errorf("polygon without any ring")
+	}
+
+	polygon := make(Polygon, len(coordinates))
+	for i, ring := range coordinates {
+		if len(ring) < 3 {
+			return nil, fmt.Errorf("ring with %d positions, at least 3 are required", len(ring))
+		}
+
+		polygon[i] = make([]Point, len(ring))
+		for j, position := range ring {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("position with %d coordinates, at least 2 are required", len(position))
+			}
+			polygon[i][j] = Point{Lat: position[1], Lon: position[0]}
+		}
+	}
+
+	return polygon, nil
+}
@@ -6,23 +6,50 @@ const (
 	EARTH_RADIUS_KM = 6371
 )
 
-var POLYGON = []Point{
-	{Lat: 39.7153328, Lon: 1.1861908},
-	{Lat: 39.7097536, Lon: 0.3860986},
-	{Lat: 39.7049828, Lon: -1.2260914},
-	{Lat: 37.8525431, Lon: -1.2438369},
-	{Lat: 37.8358186, Lon: 1.1625552},
-}
-
 type GeoPoint struct {
 	Lat   float64
 	Lon   float64
+	// Value is NaN when there is no data for the point
 	Value float64
+	// Components of a vector value like the wind, nil for scalars
+	Components []float64
 }
 
 type Point struct {
-	Lat float64
-	Lon float64
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Polygon is an outer ring followed by the rings of its holes, like in GeoJSON
+type Polygon [][]Point
+
+// MultiPolygon is a set of polygons, like an administrative boundary with islands or enclaves
+type MultiPolygon []Polygon
+
+// Contains tells whether a point is inside the outer ring and outside of every hole
+func (p Polygon) Contains(point Point) bool {
+	if len(p) == 0 || !IsPointInPolygon(point, p[0]) {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if IsPointInPolygon(point, hole) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Contains tells whether a point is inside any of the polygons
+func (m MultiPolygon) Contains(point Point) bool {
+	for _, polygon := range m {
+		if polygon.Contains(point) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func IsPointInPolygon(point Point, polygon []Point) bool {
@@ -30,6 +57,10 @@ func IsPointInPolygon(point Point, polygon []Point) bool {
 	n := len(polygon)
 	inside := false
 
+	if n == 0 {
+		return false
+	}
+
 	p1x, p1y := polygon[0].Lon, polygon[0].Lat
 	for i := 1; i <= n; i++ {
 		p2x, p2y := polygon[i%n].Lon, polygon[i%n].Lat
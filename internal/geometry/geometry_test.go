@@ -0,0 +1,158 @@
+package geometry
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMultiPolygonContains(t *testing.T) {
+	// A province with a lake in the middle and a detached enclave, like Rincón de Ademuz
+	area, err := ParseGeoJSON([]byte(`{
+		"type": "FeatureCollection",
+		"features": [
+			{
+				"type": "Feature",
+				"properties": {"name": "province"},
+				"geometry": {
+					"type": "MultiPolygon",
+					"coordinates": [
+						[
+							[[0, 38], [2, 38], [2, 40], [0, 40], [0, 38]],
+							[[0.5, 38.5], [1.5, 38.5], [1.5, 39.5], [0.5, 39.5], [0.5, 38.5]]
+						],
+						[
+							[[-1.5, 40], [-1, 40], [-1, 40.5], [-1.5, 40.5], [-1.5, 40]]
+						]
+					]
+				}
+			}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		point    Point
+		expected bool
+	}{
+		{name: "Inside the outer ring", point: Point{Lat: 38.2, Lon: 0.2}, expected: true},
+		{name: "Inside the hole", point: Point{Lat: 39, Lon: 1}, expected: false},
+		{name: "Inside the enclave", point: Point{Lat: 40.2, Lon: -1.2}, expected: true},
+		{name: "Between the province and the enclave", point: Point{Lat: 40.2, Lon: -0.5}, expected: false},
+		{name: "Outside everything", point: Point{Lat: 45, Lon: 5}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := area.Contains(tc.point); result != tc.expected {
+				t.Errorf("Contains(%v) = %v; want %v", tc.point, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		geoJSON string
+	}{
+		{name: "No polygon", geoJSON: `{"type": "Point", "coordinates": [0, 38]}`},
+		{name: "Unknown type", geoJSON: `{"type": "Polygone", "coordinates": []}`},
+		{name: "Ring too short", geoJSON: `{"type": "Polygon", "coordinates": [[[0, 38], [2, 38]]]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseGeoJSON([]byte(tc.geoJSON)); err == nil {
+				t.Errorf("ParseGeoJSON(%s) returned no error", tc.geoJSON)
+			}
+		})
+	}
+}
+
+func TestMaskedAreaInside(t *testing.T) {
+	area := NewMaskedArea(MultiPolygon{
+		{{{Lat: 38, Lon: 0}, {Lat: 38, Lon: 2}, {Lat: 40, Lon: 2}, {Lat: 40, Lon: 0}}},
+	})
+
+	// From 40.5N to 37.5N and from 359.5E (-0.5) to 2.5E, like GRIB files store them
+	grid := GridDefinition{Nx: 7, Ny: 7, FirstLat: 40.5, FirstLon: 359.5, DLat: -0.5, DLon: 0.5}
+
+	inside := area.Inside(grid)
+	for i := 0; i < grid.Len(); i++ {
+		point := grid.Point(i)
+		expected := area.Area.Contains(point)
+		if slices.Contains(inside, i) != expected {
+			t.Errorf("point %v inside = %v; want %v", point, !expected, expected)
+		}
+	}
+
+	// The grid is the same for every field and hour, the mask must be reused
+	if again := area.Inside(grid); &again[0] != &inside[0] {
+		t.Errorf("mask was computed again for the same grid")
+	}
+}
+
+func TestGridPoint(t *testing.T) {
+	grid := GridDefinition{Nx: 3, Ny: 2, FirstLat: 40, FirstLon: 359, DLat: -1, DLon: 1}
+
+	testCases := []struct {
+		index    int
+		expected Point
+	}{
+		{index: 0, expected: Point{Lat: 40, Lon: -1}},
+		{index: 1, expected: Point{Lat: 40, Lon: 0}},
+		{index: 2, expected: Point{Lat: 40, Lon: 1}},
+		{index: 5, expected: Point{Lat: 39, Lon: 1}},
+	}
+
+	for _, tc := range testCases {
+		if point := grid.Point(tc.index); point != tc.expected {
+			t.Errorf("Point(%d) = %v; want %v", tc.index, point, tc.expected)
+		}
+	}
+}
+
+func TestGridDefinitionSubGrid(t *testing.T) {
+	// Like AROME: 0.5 degree steps from 55N 348E (-12) going south and east
+	grid := GridDefinition{Nx: 57, Ny: 41, FirstLat: 55, FirstLon: 348, DLat: -0.5, DLon: 0.5}
+
+	testCases := []struct {
+		name       string
+		bounds     BoundingBox
+		expected   GridDefinition
+		expectedX0 int
+		expectedY0 int
+	}{
+		{
+			name:       "Bounds on grid points",
+			bounds:     BoundingBox{MinLat: 38, MinLon: -1, MaxLat: 40, MaxLon: 1},
+			expected:   GridDefinition{Nx: 5, Ny: 5, FirstLat: 40, FirstLon: 359, DLat: -0.5, DLon: 0.5},
+			expectedX0: 22,
+			expectedY0: 30,
+		},
+		{
+			name:       "Bounds between grid points",
+			bounds:     BoundingBox{MinLat: 38.2, MinLon: -0.8, MaxLat: 39.1, MaxLon: 0.2},
+			expected:   GridDefinition{Nx: 4, Ny: 4, FirstLat: 39.5, FirstLon: 359, DLat: -0.5, DLon: 0.5},
+			expectedX0: 22,
+			expectedY0: 31,
+		},
+		{
+			name:     "Bounds outside of the grid",
+			bounds:   BoundingBox{MinLat: 10, MinLon: 50, MaxLat: 11, MaxLon: 51},
+			expected: GridDefinition{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			subGrid, x0, y0 := grid.SubGrid(tc.bounds)
+			if subGrid != tc.expected || x0 != tc.expectedX0 || y0 != tc.expectedY0 {
+				t.Errorf("SubGrid() = %+v, %d, %d; want %+v, %d, %d", subGrid, x0, y0, tc.expected, tc.expectedX0, tc.expectedY0)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package geometry
+
+import "math"
+
+// GridDefinition describes a regular lat/lon grid, like the ones of AROME.
+// Points are stored row by row: point i is at row i/Nx and column i%Nx.
+type GridDefinition struct {
+	Nx int
+	Ny int
+	// FirstLat and FirstLon are the coordinates of the first point, in degrees
+	FirstLat float64
+	FirstLon float64
+	// DLat and DLon are the steps between rows and columns, DLat is negative when rows go from north to south
+	DLat float64
+	DLon float64
+}
+
+// Grid holds the values of a field, one float32 per point of its definition
+type Grid struct {
+	GridDefinition
+	Values []float32
+	// Missing tells which points have no value, it is nil when every point has one
+	Missing []bool
+}
+
+func (d GridDefinition) Len() int {
+	return d.Nx * d.Ny
+}
+
+// Point returns the coordinates of point i, longitudes are between -180 and 180
+func (d GridDefinition) Point(i int) Point {
+	lat := d.FirstLat + float64(i/d.Nx)*d.DLat
+	lon := d.FirstLon + float64(i%d.Nx)*d.DLon
+
+	// GRIB stores longitudes between 0 and 360 but regions are given between -180 and 180
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+
+	return Point{Lat: lat, Lon: lon - 180}
+}
+
+// SubGrid returns the smallest part of the grid covering bounds, along with the column and row of its first point.
+// The sub-grid is empty when bounds are outside of the grid.
+func (d GridDefinition) SubGrid(bounds BoundingBox) (GridDefinition, int, int) {
+	if d.DLat == 0 || d.DLon == 0 {
+		return d, 0, 0
+	}
+
+	// Longitudes are compared relative to the first one so that 348E and -12 are the same
+	relativeLon := func(lon float64) float64 {
+		return math.Mod(math.Mod(lon-d.FirstLon+180, 360)+360, 360) - 180
+	}
+
+	x0, x1, validX := indexRange(relativeLon(bounds.MinLon)/d.DLon, relativeLon(bounds.MaxLon)/d.DLon, d.Nx)
+	y0, y1, validY := indexRange((bounds.MinLat-d.FirstLat)/d.DLat, (bounds.MaxLat-d.FirstLat)/d.DLat, d.Ny)
+	if !validX || !validY {
+		return GridDefinition{}, 0, 0
+	}
+
+	return GridDefinition{
+		Nx:       x1 - x0 + 1,
+		Ny:       y1 - y0 + 1,
+		FirstLat: d.FirstLat + float64(y0)*d.DLat,
+		FirstLon: d.FirstLon + float64(x0)*d.DLon,
+		DLat:     d.DLat,
+		DLon:     d.DLon,
+	}, x0, y0
+}
+
+// indexRange returns the indexes between a and b, widened to whole points and limited to [0, n)
+func indexRange(a float64, b float64, n int) (int, int, bool) {
+	// Rounding errors shouldn't add a row or a column when bounds are exactly on a point
+	const epsilon = 1e-6
+	first := int(math.Max(math.Floor(math.Min(a, b)+epsilon), 0))
+	last := int(math.Min(math.Ceil(math.Max(a, b)-epsilon), float64(n-1)))
+
+	return first, last, first <= last
+}
+
+func (g *Grid) IsMissing(i int) bool {
+	return g.Missing != nil && g.Missing[i]
+}
+
+// Crop returns the part of the grid covering bounds, see SubGrid
+func (g *Grid) Crop(bounds BoundingBox) *Grid {
+	subGrid, x0, y0 := g.SubGrid(bounds)
+	cropped := &Grid{GridDefinition: subGrid, Values: make([]float32, 0, subGrid.Len())}
+	if g.Missing != nil {
+		cropped.Missing = make([]bool, 0, subGrid.Len())
+	}
+
+	for y := y0; y < y0+subGrid.Ny; y++ {
+		row := y*g.Nx + x0
+		cropped.Values = append(cropped.Values, g.Values[row:row+subGrid.Nx]...)
+		if g.Missing != nil {
+			cropped.Missing = append(cropped.Missing, g.Missing[row:row+subGrid.Nx]...)
+		}
+	}
+
+	return cropped
+}
@@ -0,0 +1,111 @@
+package geometry
+
+import (
+	"math"
+	"sync"
+)
+
+// BoundingBox is the smallest lat/lon rectangle containing an area
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+func (b BoundingBox) Contains(point Point) bool {
+	return point.Lat >= b.MinLat && point.Lat <= b.MaxLat && point.Lon >= b.MinLon && point.Lon <= b.MaxLon
+}
+
+// Union returns the bounding box containing both boxes
+func (b BoundingBox) Union(other BoundingBox) BoundingBox {
+	return BoundingBox{
+		MinLat: math.Min(b.MinLat, other.MinLat),
+		MinLon: math.Min(b.MinLon, other.MinLon),
+		MaxLat: math.Max(b.MaxLat, other.MaxLat),
+		MaxLon: math.Max(b.MaxLon, other.MaxLon),
+	}
+}
+
+// Bounds returns the bounding box of the outer rings of the polygons
+func (m MultiPolygon) Bounds() BoundingBox {
+	bounds := BoundingBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	for _, polygon := range m {
+		if len(polygon) == 0 {
+			continue
+		}
+		for _, point := range polygon[0] {
+			bounds.MinLat = math.Min(bounds.MinLat, point.Lat)
+			bounds.MinLon = math.Min(bounds.MinLon, point.Lon)
+			bounds.MaxLat = math.Max(bounds.MaxLat, point.Lat)
+			bounds.MaxLon = math.Max(bounds.MaxLon, point.Lon)
+		}
+	}
+
+	return bounds
+}
+
+// MaskedArea is an area that remembers which points of each grid are inside of it, so that
+// polygons are tested once per grid instead of once per point, field and hour.
+type MaskedArea struct {
+	Area MultiPolygon
+
+	bounds        BoundingBox
+	polygonBounds []BoundingBox
+
+	mu    sync.Mutex
+	masks map[GridDefinition][]int
+}
+
+func NewMaskedArea(area MultiPolygon) *MaskedArea {
+	polygonBounds := make([]BoundingBox, len(area))
+	for i, polygon := range area {
+		polygonBounds[i] = MultiPolygon{polygon}.Bounds()
+	}
+
+	return &MaskedArea{
+		Area:          area,
+		bounds:        area.Bounds(),
+		polygonBounds: polygonBounds,
+		masks:         make(map[GridDefinition][]int),
+	}
+}
+
+func (a *MaskedArea) Bounds() BoundingBox {
+	return a.bounds
+}
+
+// Contains is like MultiPolygon.Contains with bounding boxes checked first
+func (a *MaskedArea) Contains(point Point) bool {
+	if !a.bounds.Contains(point) {
+		return false
+	}
+
+	for i, polygon := range a.Area {
+		if a.polygonBounds[i].Contains(point) && polygon.Contains(point) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Inside returns the indexes of the points of a grid inside the area, computed once per grid definition
+func (a *MaskedArea) Inside(grid GridDefinition) []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if indexes, exists := a.masks[grid]; exists {
+		return indexes
+	}
+
+	indexes := []int{}
+	for i := 0; i < grid.Len(); i++ {
+		if a.Contains(grid.Point(i)) {
+			indexes = append(indexes, i)
+		}
+	}
+	a.masks[grid] = indexes
+
+	return indexes
+}
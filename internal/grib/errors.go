@@ -0,0 +1,31 @@
+package grib
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCorruptMessage is returned when a file isn't made of valid GRIB messages, e.g. a truncated download
+	ErrCorruptMessage = errors.New("corrupt GRIB message")
+	// ErrFieldNotFound is returned when no message of a file matches a selector
+	ErrFieldNotFound = errors.New("GRIB field not found")
+	// ErrAmbiguousField is returned when several messages of a file match a selector
+	ErrAmbiguousField = errors.New("ambiguous GRIB field")
+	// ErrUnsupportedGrid is returned for grids other than regular lat/lon ones
+	ErrUnsupportedGrid = errors.New("unsupported GRIB grid")
+	// ErrUnsupportedTemplate is returned by the Go decoder for messages it can't decode, ecCodes can
+	ErrUnsupportedTemplate = errors.New("unsupported GRIB template")
+)
+
+// CodesError is an error code returned by ecCodes along with its description
+type CodesError struct {
+	// Op is what was being done, like "getting values"
+	Op      string
+	Code    int
+	Message string
+}
+
+func (e *CodesError) Error() string {
+	return fmt.Sprintf("%s: ecCodes error %d: %s", e.Op, e.Code, e.Message)
+}
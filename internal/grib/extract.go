@@ -0,0 +1,63 @@
+package grib
+
+import (
+	"fmt"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// ExtractGribData returns the grid of the message selected by each selector, by selector key.
+// A selector matching several messages is an error since their values can't be told apart.
+// When bounds are given, grids only cover them (see geometry.GridDefinition.SubGrid).
+// A selector matching no message is an error, unless it is optional.
+func ExtractGribData(filename string, selectors []Selector, bounds *geometry.BoundingBox) (map[string]*geometry.Grid, error) {
+	reader, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	gridsByField := make(map[string]*geometry.Grid)
+	matchedBy := make(map[string]Metadata)
+
+	for reader.Next() {
+		message := reader.Message()
+		metadata, err := message.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		// A message selected under several keys is only decoded once
+		var grid *geometry.Grid
+
+		for _, selector := range selectors {
+			if !selector.Matches(metadata) {
+				continue
+			}
+
+			if previous, exists := matchedBy[selector.Key()]; exists {
+				return nil, fmt.Errorf("%w: %q (%s) matches several messages in %s: %s and %s, set typeOfLevel, level or stepType to pick one", ErrAmbiguousField, selector.Key(), selector, filename, describeMessage(previous), describeMessage(metadata))
+			}
+			matchedBy[selector.Key()] = metadata
+
+			if grid == nil {
+				grid, err = message.Grid(bounds)
+				if err != nil {
+					return nil, err
+				}
+			}
+			gridsByField[selector.Key()] = grid
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, selector := range selectors {
+		if _, exists := gridsByField[selector.Key()]; !exists && !selector.Optional {
+			return nil, fmt.Errorf("%w: %q (%s) in %s", ErrFieldNotFound, selector.Key(), selector, filename)
+		}
+	}
+
+	return gridsByField, nil
+}
@@ -0,0 +1,49 @@
+//go:build purego
+
+package grib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+func TestExtractGribDataWithoutEcCodes(t *testing.T) {
+	temperature := testField{category: 0, number: 0, surfaceType: 103, level: 2, step: 1, values: []float64{293.15, 293.4, 292.85, 291.5, 290.05, 288.95}, decimalScale: 2, packing: 3}
+	humidity := testField{category: 1, number: 1, surfaceType: 103, level: 2, step: 1, values: []float64{55, 60, 65, 70, 75, 80}, packing: 2}
+
+	filename := filepath.Join(t.TempDir(), "file.grib2")
+	if err := os.WriteFile(filename, append(temperature.encode(), humidity.encode()...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	two := 2
+	selectors := []Selector{{ShortName: "2t", As: "t2m"}, {ShortName: "2r", TypeOfLevel: "heightAboveGround", Level: &two}}
+	// Only the eastern column of the 3x2 grid
+	bounds := &geometry.BoundingBox{MinLat: 39.5, MinLon: 0, MaxLat: 40, MaxLon: 0}
+
+	grids, err := ExtractGribData(filename, selectors, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if grid := grids["t2m"]; grid.Nx != 1 || grid.Ny != 2 || grid.Values[0] != float32(292.85) || grid.Values[1] != float32(288.95) {
+		t.Errorf("t2m = %+v; want the eastern column, 292.85 and 288.95", grid)
+	}
+	if grid := grids["2r"]; len(grid.Values) != 2 || grid.Values[0] != 65 || grid.Values[1] != 80 {
+		t.Errorf("2r = %+v; want the eastern column, 65 and 80", grid)
+	}
+
+	_, err = ExtractGribData(filename, []Selector{{ShortName: "10u"}}, nil)
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("ExtractGribData(10u) = %v; want %v", err, ErrFieldNotFound)
+	}
+
+	grids, err = ExtractGribData(filename, []Selector{{ShortName: "2t"}, {ShortName: "10fg", Optional: true}}, nil)
+	if _, exists := grids["10fg"]; err != nil || exists || grids["2t"] == nil {
+		t.Errorf("ExtractGribData(2t, optional 10fg) = %v, %v; want only 2t", grids, err)
+	}
+}
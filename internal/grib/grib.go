@@ -1,3 +1,5 @@
+//go:build !purego
+
 package grib
 
 /*
@@ -9,140 +11,155 @@ import "C"
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"strings"
 	"unsafe"
 
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
 )
 
+// readGrid reads the values of a message in bulk along with the definition of its grid,
+// only the part of the grid covering bounds is read when they are given
+func readGrid(handle *C.codes_handle, bounds *geometry.BoundingBox) (*geometry.Grid, error) {
+	if gridType := getString(handle, "gridType"); gridType != "regular_ll" {
+		return nil, fmt.Errorf("%w %q, only regular_ll is supported", ErrUnsupportedGrid, gridType)
+	}
 
-func checkErr(msg string, err C.int) {
-	if err != 0 {
-		log.Fatalf("%s: error code %d", msg, err)
+	longs := map[string]int64{}
+	for _, key := range []string{"Ni", "Nj", "iScansNegatively", "jScansPositively", "jPointsAreConsecutive", "bitmapPresent"} {
+		value, err := requireLong(handle, key)
+		if err != nil {
+			return nil, err
+		}
+		longs[key] = value
 	}
-}
 
-func ExtractGribData(filename string, fields []string) (map[string][]geometry.GeoPoint, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+	doubles := map[string]float64{}
+	for _, key := range []string{"latitudeOfFirstGridPointInDegrees", "longitudeOfFirstGridPointInDegrees", "iDirectionIncrementInDegrees", "jDirectionIncrementInDegrees", "missingValue"} {
+		value, err := requireDouble(handle, key)
+		if err != nil {
+			return nil, err
+		}
+		doubles[key] = value
 	}
-	defer file.Close()
-	
-	stat, err := file.Stat()
-	if err != nil {
-		log.Fatal(err)
+
+	if longs["jPointsAreConsecutive"] != 0 {
+		return nil, fmt.Errorf("%w: columns stored before rows", ErrUnsupportedGrid)
+	}
+
+	definition := geometry.GridDefinition{
+		Nx:       int(longs["Ni"]),
+		Ny:       int(longs["Nj"]),
+		FirstLat: doubles["latitudeOfFirstGridPointInDegrees"],
+		FirstLon: doubles["longitudeOfFirstGridPointInDegrees"],
+		DLat:     doubles["jDirectionIncrementInDegrees"],
+		DLon:     doubles["iDirectionIncrementInDegrees"],
+	}
+	if longs["jScansPositively"] == 0 {
+		definition.DLat = -definition.DLat
+	}
+	if longs["iScansNegatively"] != 0 {
+		definition.DLon = -definition.DLon
 	}
 
-	buf := make([]byte, stat.Size())
-	_, err = file.Read(buf)
+	key := C.CString("values")
+	defer C.free(unsafe.Pointer(key))
+
+	var size C.size_t
+	if errCode := C.codes_get_size(handle, key, &size); errCode != 0 {
+		return nil, newCodesError("getting the number of values", errCode)
+	}
+	if int(size) != definition.Len() {
+		return nil, fmt.Errorf("%w: %d values for a %dx%d grid", ErrCorruptMessage, size, definition.Nx, definition.Ny)
+	}
+
+	grid, err := readValues(handle, key, definition, bounds)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	// Process all messages in the GRIB file
-	return extractAllMessages(buf, fields)
+	// Points left out of the bitmap get the missing value, so do the ones complex packing marks as missing
+	if longs["bitmapPresent"] != 0 || getLong(handle, "missingValueManagementUsed") != 0 {
+		missingValue := float32(doubles["missingValue"])
+		grid.Missing = make([]bool, len(grid.Values))
+		for i, value := range grid.Values {
+			grid.Missing[i] = value == missingValue
+		}
+	}
+
+	return grid, nil
 }
 
-func extractAllMessages(buf []byte, fields []string) (map[string][]geometry.GeoPoint, error) {
-	pointsByField := make(map[string][]geometry.GeoPoint)
-	for _, field := range fields {
-		pointsByField[field] = []geometry.GeoPoint{}
-	}
-
-	offset := 0
-	messageCount := 0
-	
-	for offset < len(buf) {
-		cBuf := unsafe.Pointer(&buf[offset])
-		size := C.size_t(len(buf) - offset)
-		
-		var errCode C.int
-		var handle *C.codes_handle
-		
-		handle = C.codes_handle_new_from_message(nil, cBuf, size)
-		if handle == nil {
-			break // No more messages
+func readValues(handle *C.codes_handle, key *C.char, definition geometry.GridDefinition, bounds *geometry.BoundingBox) (*geometry.Grid, error) {
+	if bounds == nil {
+		grid := &geometry.Grid{GridDefinition: definition, Values: make([]float32, definition.Len())}
+		if definition.Len() == 0 {
+			return grid, nil
 		}
-		
-		messageCount++
-		
-		for _, field := range fields {
-			if shouldProcessMessage(handle, field) {
-			points, err := extractGribDataFromHandle(handle, field)
-			if err != nil {
-				C.codes_handle_delete(handle)
-				return nil, err
-			}
-				pointsByField[field] = append(pointsByField[field], points...)
-			}
+
+		// codes_get_float_array only exists since ecCodes 2.30, Debian bullseye ships 2.20
+		values := make([]C.double, definition.Len())
+		size := C.size_t(len(values))
+		if errCode := C.codes_get_double_array(handle, key, &values[0], &size); errCode != 0 {
+			return nil, newCodesError("getting values", errCode)
 		}
-		
-		var messageSize C.long
-		errCode = C.codes_get_long(handle, C.CString("totalLength"), &messageSize)
-		C.codes_handle_delete(handle)
-		
-		if errCode != 0 {
-			break
+
+		for i, value := range values {
+			grid.Values[i] = float32(value)
 		}
-		
-		offset += int(messageSize)
+
+		return grid, nil
 	}
-	
-	return pointsByField, nil
-}
 
-func shouldProcessMessage(handle *C.codes_handle, field string) bool {
-	getString := func(key string) string {
-		var sLen C.size_t = 64
-		s := (*C.char)(C.malloc(sLen))
-		defer C.free(unsafe.Pointer(s))
-		
-		errCode := C.codes_get_string(handle, C.CString(key), s, &sLen)
-		if errCode == 0 {
-			return C.GoStringN(s, C.int(sLen))
-		}
-		return ""
-	}
-	
-	variableKeys := []string{"shortName", "paramId", "variable", "cfVarName", "name"}
-	variableFound := false
-	
-	for _, key := range variableKeys {
-		value := getString(key)
-		if strings.Contains(strings.ToLower(value), field) {
-			variableFound = true
-			break
+	subGrid, x0, y0 := definition.SubGrid(*bounds)
+	grid := &geometry.Grid{GridDefinition: subGrid, Values: make([]float32, subGrid.Len())}
+	if subGrid.Len() == 0 {
+		return grid, nil
+	}
+
+	// Only ask for the points of the window, ecCodes doesn't unpack the others with simple packing
+	indexes := make([]C.int, 0, subGrid.Len())
+	for y := 0; y < subGrid.Ny; y++ {
+		for x := 0; x < subGrid.Nx; x++ {
+			indexes = append(indexes, C.int((y0+y)*definition.Nx+x0+x))
 		}
 	}
-	
-	return variableFound
+
+	values := make([]C.double, len(indexes))
+	if errCode := C.codes_get_double_elements(handle, key, &indexes[0], C.long(len(indexes)), &values[0]); errCode != 0 {
+		return nil, newCodesError("getting values", errCode)
+	}
+
+	for i, value := range values {
+		grid.Values[i] = float32(value)
+	}
+
+	return grid, nil
 }
 
-func extractGribDataFromHandle(handle *C.codes_handle, field string) ([]geometry.GeoPoint, error) {
-	var numberOfPoints C.long
-	errCode := C.codes_get_long(handle, C.CString("numberOfPoints"), &numberOfPoints)
-	checkErr("getting numberOfPoints", errCode)
+func requireLong(handle *C.codes_handle, key string) (int64, error) {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
 
-	var err C.int
-	iter := C.codes_grib_iterator_new(handle, 0, &err)
-	if iter == nil {
-		return nil, fmt.Errorf("failed to create iterator: error code %d", err)
+	var value C.long
+	if errCode := C.codes_get_long(handle, cKey, &value); errCode != 0 {
+		return 0, newCodesError("getting "+key, errCode)
 	}
-	defer C.codes_grib_iterator_delete(iter)
 
-	var points []geometry.GeoPoint
-	var lat, lon, value C.double
+	return int64(value), nil
+}
+
+func requireDouble(handle *C.codes_handle, key string) (float64, error) {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
 
-	for C.codes_grib_iterator_next(iter, &lat, &lon, &value) == 1 {
-		points = append(points, geometry.GeoPoint{
-			Lat:   float64(lat),
-			Lon:   float64(lon),
-			Value: float64(value),
-		})
+	var value C.double
+	if errCode := C.codes_get_double(handle, cKey, &value); errCode != 0 {
+		return 0, newCodesError("getting "+key, errCode)
 	}
 
-	return points, nil
+	return float64(value), nil
+}
+
+// newCodesError describes an ecCodes error code with the text ecCodes gives for it
+func newCodesError(op string, errCode C.int) error {
+	return &CodesError{Op: op, Code: int(errCode), Message: C.GoString(C.codes_get_error_message(errCode))}
 }
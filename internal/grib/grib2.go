@@ -0,0 +1,692 @@
+package grib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// This is a GRIB2 decoder written in Go, used instead of ecCodes when building with -tags purego.
+// It only supports what AROME files use: regular lat/lon grids (template 3.0), instantaneous and
+// statistically processed products (templates 4.0 and 4.8), and simple or complex packing with or
+// without spatial differencing (templates 5.0, 5.2 and 5.3) along with a bitmap. Values packed with
+// simple packing are read point by point so a window only costs its own points, complex packing stores
+// values in groups and as differences with the previous ones so the whole grid is decoded and then cropped.
+// Octets are numbered from 1 in the WMO tables, so octet n of a section is at index n-1.
+
+const (
+	// MISSING_VALUE is the value of points left out by the bitmap, the default of ecCodes
+	MISSING_VALUE = 9999
+	// MAX_GRID_POINTS keeps a damaged grid definition from allocating gigabytes, AROME grids have about 5 million points
+	MAX_GRID_POINTS = 1 << 26
+)
+
+// grib2Message is a GRIB2 message split into its sections, each section starts with its length and number
+type grib2Message struct {
+	discipline     int
+	identification []byte
+	grid           []byte
+	product        []byte
+	representation []byte
+	bitmap         []byte
+	data           []byte
+}
+
+func parseGrib2(data []byte) (*grib2Message, error) {
+	if len(data) < INDICATOR_SECTION_LENGTH+4 {
+		return nil, fmt.Errorf("%w: message of %d bytes", ErrCorruptMessage, len(data))
+	}
+	if edition := data[7]; edition != 2 {
+		return nil, fmt.Errorf("%w: GRIB edition %d, only GRIB 2 can be decoded without ecCodes", ErrUnsupportedTemplate, edition)
+	}
+
+	message := &grib2Message{discipline: int(data[6])}
+	offset := INDICATOR_SECTION_LENGTH
+
+	for string(data[offset:offset+4]) != "7777" {
+		if offset+5 > len(data)-4 {
+			return nil, fmt.Errorf("%w: section header at %d is out of the message", ErrCorruptMessage, offset)
+		}
+
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		if length < 5 || offset+length > len(data)-4 {
+			return nil, fmt.Errorf("%w: section at %d has an invalid length of %d", ErrCorruptMessage, offset, length)
+		}
+		section := data[offset : offset+length]
+
+		switch number := section[4]; number {
+		case 1:
+			message.identification = section
+		case 2:
+			// Local use, nothing we need
+		case 3:
+			message.grid = section
+		case 4:
+			if message.product != nil {
+				return nil, fmt.Errorf("%w: several fields in a single message", ErrUnsupportedTemplate)
+			}
+			message.product = section
+		case 5:
+			message.representation = section
+		case 6:
+			message.bitmap = section
+		case 7:
+			message.data = section
+		default:
+			return nil, fmt.Errorf("%w: unknown section %d at %d", ErrCorruptMessage, number, offset)
+		}
+
+		offset += length
+	}
+
+	minimumLengths := []struct {
+		name    string
+		section []byte
+		length  int
+	}{
+		{"identification", message.identification, 21},
+		{"grid definition", message.grid, 14},
+		{"product definition", message.product, 9},
+		{"data representation", message.representation, 11},
+		{"bitmap", message.bitmap, 6},
+		{"data", message.data, 5},
+	}
+	for _, minimum := range minimumLengths {
+		if len(minimum.section) < minimum.length {
+			return nil, fmt.Errorf("%w: %s section is missing or too short", ErrCorruptMessage, minimum.name)
+		}
+	}
+
+	return message, nil
+}
+
+// gridDefinition reads a regular lat/lon grid (template 3.0)
+func (m *grib2Message) gridDefinition() (geometry.GridDefinition, error) {
+	section := m.grid
+	if template := uint16At(section, 12); template != 0 {
+		return geometry.GridDefinition{}, fmt.Errorf("%w: grid definition template 3.%d, only regular_ll is supported", ErrUnsupportedGrid, template)
+	}
+	if len(section) < 72 {
+		return geometry.GridDefinition{}, fmt.Errorf("%w: grid definition section is too short", ErrCorruptMessage)
+	}
+
+	numberOfPoints := uint64(uint32At(section, 6))
+	nx, ny := uint64(uint32At(section, 30)), uint64(uint32At(section, 34))
+	if nx*ny != numberOfPoints {
+		return geometry.GridDefinition{}, fmt.Errorf("%w: %dx%d grid with %d points", ErrCorruptMessage, nx, ny, numberOfPoints)
+	}
+	if numberOfPoints > MAX_GRID_POINTS {
+		return geometry.GridDefinition{}, fmt.Errorf("%w: grid of %d points", ErrCorruptMessage, numberOfPoints)
+	}
+
+	// Angles are in millionths of a degree unless a basic angle is given
+	unit := 1e-6
+	if basicAngle, subdivisions := uint32At(section, 38), uint32At(section, 42); basicAngle != 0 && basicAngle != math.MaxUint32 && subdivisions != 0 && subdivisions != math.MaxUint32 {
+		unit = float64(basicAngle) / float64(subdivisions)
+	}
+
+	definition := geometry.GridDefinition{
+		Nx:       int(nx),
+		Ny:       int(ny),
+		FirstLat: float64(int32At(section, 46)) * unit,
+		FirstLon: float64(int32At(section, 50)) * unit,
+		DLat:     float64(uint32At(section, 67)) * unit,
+		DLon:     float64(uint32At(section, 63)) * unit,
+	}
+
+	scanningMode := section[71]
+	if scanningMode&0x20 != 0 || scanningMode&0x10 != 0 {
+		return geometry.GridDefinition{}, fmt.Errorf("%w: scanning mode %08b", ErrUnsupportedGrid, scanningMode)
+	}
+	if scanningMode&0x80 != 0 {
+		definition.DLon = -definition.DLon
+	}
+	if scanningMode&0x40 == 0 {
+		definition.DLat = -definition.DLat
+	}
+
+	return definition, nil
+}
+
+// metadata reads the product definition (templates 4.0 and 4.8), keys are named like in ecCodes
+func (m *grib2Message) metadata() (Metadata, error) {
+	section := m.product
+	template := uint16At(section, 7)
+	if template != 0 && template != 8 {
+		return Metadata{}, fmt.Errorf("%w: product definition template 4.%d", ErrUnsupportedTemplate, template)
+	}
+	if (template == 0 && len(section) < 34) || (template == 8 && len(section) < 58) {
+		return Metadata{}, fmt.Errorf("%w: product definition section is too short", ErrCorruptMessage)
+	}
+
+	typeOfLevel, level := surface(section[22], section[23], uint32At(section, 24))
+
+	timeUnit, err := timeRangeUnit(section[17])
+	if err != nil {
+		return Metadata{}, err
+	}
+	start := time.Duration(uint32At(section, 18)) * timeUnit
+
+	metadata := Metadata{
+		TypeOfLevel: typeOfLevel,
+		Level:       level,
+		StepType:    "instant",
+		StepRange:   formatStep(start),
+	}
+
+	identification := m.identification
+	referenceTime := time.Date(int(uint16At(identification, 12)), time.Month(identification[14]), int(identification[15]), int(identification[16]), int(identification[17]), int(identification[18]), 0, time.UTC)
+	metadata.ValidityTime = referenceTime.Add(start)
+
+	if template == 8 {
+		metadata.StepType = statisticalStepType(section[46])
+
+		lengthUnit, err := timeRangeUnit(section[48])
+		if err != nil {
+			return Metadata{}, err
+		}
+		end := start + time.Duration(uint32At(section, 49))*lengthUnit
+
+		metadata.StepRange = formatStep(start) + "-" + formatStep(end)
+		metadata.ValidityTime = time.Date(int(uint16At(section, 34)), time.Month(section[36]), int(section[37]), int(section[38]), int(section[39]), int(section[40]), 0, time.UTC)
+	}
+
+	parameter := lookupParameter(m.discipline, int(section[9]), int(section[10]), typeOfLevel, level, metadata.StepType)
+	metadata.ShortName = parameter.shortName
+	metadata.Name = parameter.name
+	metadata.Units = parameter.units
+
+	return metadata, nil
+}
+
+// packing describes how the values of the data section are packed (section 5)
+type packing struct {
+	template       uint16
+	numberOfValues int
+	reference      float64
+	binaryScale    float64
+	decimalScale   float64
+	bitsPerValue   int
+}
+
+// packing reads the data representation and checks it fits the grid and the data section
+// before anything is allocated from the sizes it gives
+func (m *grib2Message) packing(numberOfPoints int) (packing, error) {
+	section := m.representation
+	template := uint16At(section, 9)
+	if template != 0 && template != 2 && template != 3 {
+		return packing{}, fmt.Errorf("%w: data representation template 5.%d, only simple and complex packing are supported", ErrUnsupportedTemplate, template)
+	}
+
+	minimumLength := map[uint16]int{0: 21, 2: 47, 3: 49}[template]
+	if len(section) < minimumLength {
+		return packing{}, fmt.Errorf("%w: data representation section is too short", ErrCorruptMessage)
+	}
+
+	p := packing{
+		template:       template,
+		numberOfValues: int(uint32At(section, 5)),
+		reference:      float64(math.Float32frombits(binary.BigEndian.Uint32(section[11:]))),
+		binaryScale:    math.Pow(2, float64(int16At(section, 15))),
+		decimalScale:   math.Pow(10, -float64(int16At(section, 17))),
+		bitsPerValue:   int(section[19]),
+	}
+
+	if p.numberOfValues > numberOfPoints {
+		return packing{}, fmt.Errorf("%w: %d values for %d points", ErrCorruptMessage, p.numberOfValues, numberOfPoints)
+	}
+	if p.bitsPerValue > 63 {
+		return packing{}, fmt.Errorf("%w: values of %d bits", ErrCorruptMessage, p.bitsPerValue)
+	}
+	if dataBits := (len(m.data) - 5) * 8; template == 0 && p.numberOfValues*p.bitsPerValue > dataBits {
+		return packing{}, fmt.Errorf("%w: data section holds %d bits for %d values of %d bits", ErrCorruptMessage, dataBits, p.numberOfValues, p.bitsPerValue)
+	}
+
+	return p, nil
+}
+
+// value turns a packed integer into the value it stands for, with the same formula and order of operations as ecCodes
+func (p packing) value(packed int64) float32 {
+	return float32((float64(packed)*p.binaryScale + p.reference) * p.decimalScale)
+}
+
+// values unpacks the data of every point of the grid, points left out by the bitmap are missing
+func (m *grib2Message) values(numberOfPoints int) ([]float32, []bool, error) {
+	p, err := m.packing(numberOfPoints)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var packed []int64
+	var packedMissing []bool
+	if p.template == 0 {
+		packed, err = unpackSimple(m.data[5:], p.numberOfValues, p.bitsPerValue)
+	} else {
+		packed, packedMissing, err = unpackComplex(m.representation, m.data[5:], p.numberOfValues, p.bitsPerValue, p.template == 3)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	present, err := m.expandBitmap(numberOfPoints, p.numberOfValues)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]float32, numberOfPoints)
+	var missing []bool
+	if present != nil || packedMissing != nil {
+		missing = make([]bool, numberOfPoints)
+	}
+
+	next := 0
+	for i := range values {
+		if present != nil && !present[i] {
+			values[i] = MISSING_VALUE
+			missing[i] = true
+			continue
+		}
+
+		if packedMissing != nil && packedMissing[next] {
+			values[i] = MISSING_VALUE
+			missing[i] = true
+		} else {
+			values[i] = p.value(packed[next])
+		}
+		next++
+	}
+
+	return values, missing, nil
+}
+
+// simpleWindow reads the values of the points of a window straight from a data section with simple packing.
+// Points are visited in the order of the grid, so the bitmap is only walked once to find where their values are.
+func (m *grib2Message) simpleWindow(p packing, definition geometry.GridDefinition, subGrid geometry.GridDefinition, x0 int, y0 int) ([]float32, []bool, error) {
+	var bitmap []byte
+	switch indicator := m.bitmap[5]; indicator {
+	case 255:
+		if p.numberOfValues != definition.Len() {
+			return nil, nil, fmt.Errorf("%w: %d values for %d points without a bitmap", ErrCorruptMessage, p.numberOfValues, definition.Len())
+		}
+	case 0:
+		bitmap = m.bitmap[6:]
+		if len(bitmap)*8 < definition.Len() {
+			return nil, nil, fmt.Errorf("%w: bitmap of %d bits for %d points", ErrCorruptMessage, len(bitmap)*8, definition.Len())
+		}
+	default:
+		return nil, nil, fmt.Errorf("%w: bitmap indicator %d", ErrUnsupportedTemplate, indicator)
+	}
+
+	values := make([]float32, subGrid.Len())
+	var missing []bool
+	if bitmap != nil {
+		missing = make([]bool, subGrid.Len())
+	}
+
+	// With a bitmap, the value of a point comes after those of the points present before it
+	walked, present := 0, 0
+	for y := 0; y < subGrid.Ny; y++ {
+		for x := 0; x < subGrid.Nx; x++ {
+			i := y*subGrid.Nx + x
+			point := (y0+y)*definition.Nx + x0 + x
+
+			index := point
+			if bitmap != nil {
+				for ; walked < point; walked++ {
+					if bitmap[walked/8]&(0x80>>(walked%8)) != 0 {
+						present++
+					}
+				}
+				if bitmap[point/8]&(0x80>>(point%8)) == 0 {
+					values[i] = MISSING_VALUE
+					missing[i] = true
+					continue
+				}
+				index = present
+			}
+
+			if index >= p.numberOfValues {
+				return nil, nil, fmt.Errorf("%w: bitmap has more points than the %d values", ErrCorruptMessage, p.numberOfValues)
+			}
+			if p.bitsPerValue == 0 {
+				values[i] = p.value(0)
+				continue
+			}
+
+			reader := bitReader{data: m.data[5:], offset: index * p.bitsPerValue}
+			packed, err := reader.read(p.bitsPerValue)
+			if err != nil {
+				return nil, nil, err
+			}
+			values[i] = p.value(int64(packed))
+		}
+	}
+
+	return values, missing, nil
+}
+
+// expandBitmap returns which points have a value, or nil when they all have one
+func (m *grib2Message) expandBitmap(numberOfPoints int, numberOfValues int) ([]bool, error) {
+	switch indicator := m.bitmap[5]; indicator {
+	case 255:
+		if numberOfValues != numberOfPoints {
+			return nil, fmt.Errorf("%w: %d values for %d points without a bitmap", ErrCorruptMessage, numberOfValues, numberOfPoints)
+		}
+		return nil, nil
+	case 0:
+		bits := m.bitmap[6:]
+		if len(bits)*8 < numberOfPoints {
+			return nil, fmt.Errorf("%w: bitmap of %d bits for %d points", ErrCorruptMessage, len(bits)*8, numberOfPoints)
+		}
+
+		present := make([]bool, numberOfPoints)
+		count := 0
+		for i := range present {
+			present[i] = bits[i/8]&(0x80>>(i%8)) != 0
+			if present[i] {
+				count++
+			}
+		}
+		if count != numberOfValues {
+			return nil, fmt.Errorf("%w: bitmap has %d points for %d values", ErrCorruptMessage, count, numberOfValues)
+		}
+
+		return present, nil
+	default:
+		return nil, fmt.Errorf("%w: bitmap indicator %d", ErrUnsupportedTemplate, indicator)
+	}
+}
+
+// unpackSimple reads numberOfValues integers of bitsPerValue bits (template 5.0)
+func unpackSimple(data []byte, numberOfValues int, bitsPerValue int) ([]int64, error) {
+	packed := make([]int64, numberOfValues)
+	if bitsPerValue == 0 {
+		// Constant field, every value is the reference value
+		return packed, nil
+	}
+
+	reader := bitReader{data: data}
+	for i := range packed {
+		value, err := reader.read(bitsPerValue)
+		if err != nil {
+			return nil, err
+		}
+		packed[i] = int64(value)
+	}
+
+	return packed, nil
+}
+
+// unpackComplex reads values packed in groups (template 5.2) and undoes spatial differencing (template 5.3),
+// following the layout of the data section described in the WMO manual and used by g2clib.
+func unpackComplex(section []byte, data []byte, numberOfValues int, bitsPerValue int, spatialDifferencing bool) ([]int64, []bool, error) {
+	missingManagement := section[22]
+	if missingManagement > 2 {
+		return nil, nil, fmt.Errorf("%w: missing value management %d", ErrUnsupportedTemplate, missingManagement)
+	}
+
+	numberOfGroups := int(uint32At(section, 31))
+	widthReference := int(section[35])
+	widthBits := int(section[36])
+	lengthReference := int(uint32At(section, 37))
+	lengthIncrement := int(section[41])
+	lastGroupLength := int(uint32At(section, 42))
+	lengthBits := int(section[46])
+
+	// Every group holds at least a value and its reference, width and length take some bits of the data section
+	if numberOfGroups > numberOfValues || numberOfGroups*(bitsPerValue+widthBits+lengthBits) > len(data)*8 {
+		return nil, nil, fmt.Errorf("%w: %d groups for %d values in %d bytes", ErrCorruptMessage, numberOfGroups, numberOfValues, len(data))
+	}
+
+	reader := bitReader{data: data}
+
+	var order int
+	var firstValues []int64
+	var minimum int64
+	if spatialDifferencing {
+		order = int(section[47])
+		if order != 1 && order != 2 {
+			return nil, nil, fmt.Errorf("%w: spatial differencing of order %d", ErrUnsupportedTemplate, order)
+		}
+
+		descriptorBits := int(section[48]) * 8
+		for i := 0; i < order; i++ {
+			value, err := reader.readSigned(descriptorBits)
+			if err != nil {
+				return nil, nil, err
+			}
+			firstValues = append(firstValues, value)
+		}
+
+		var err error
+		if minimum, err = reader.readSigned(descriptorBits); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	readGroupValues := func(bits int, transform func(int) int) ([]int, error) {
+		values := make([]int, numberOfGroups)
+		for j := range values {
+			value, err := reader.read(bits)
+			if err != nil {
+				return nil, err
+			}
+			values[j] = transform(int(value))
+		}
+		reader.align()
+
+		return values, nil
+	}
+
+	references, err := readGroupValues(bitsPerValue, func(value int) int { return value })
+	if err != nil {
+		return nil, nil, err
+	}
+	widths, err := readGroupValues(widthBits, func(value int) int { return value + widthReference })
+	if err != nil {
+		return nil, nil, err
+	}
+	lengths, err := readGroupValues(lengthBits, func(value int) int { return value*lengthIncrement + lengthReference })
+	if err != nil {
+		return nil, nil, err
+	}
+	if numberOfGroups > 0 {
+		lengths[numberOfGroups-1] = lastGroupLength
+	}
+
+	total := 0
+	for _, length := range lengths {
+		total += length
+	}
+	if total != numberOfValues {
+		return nil, nil, fmt.Errorf("%w: groups hold %d values instead of %d", ErrCorruptMessage, total, numberOfValues)
+	}
+
+	packed := make([]int64, 0, numberOfValues)
+	var missing []bool
+	if missingManagement != 0 {
+		missing = make([]bool, 0, numberOfValues)
+	}
+
+	// With missing value management, all bits set is the primary missing value and all bits but the last the secondary one
+	isMissing := func(value uint64, bits int) bool {
+		if missingManagement == 0 || bits == 0 {
+			return false
+		}
+		allSet := uint64(1)<<bits - 1
+
+		return value == allSet || (missingManagement == 2 && value == allSet-1)
+	}
+
+	for j := 0; j < numberOfGroups; j++ {
+		for k := 0; k < lengths[j]; k++ {
+			var value uint64
+			groupMissing := false
+			if widths[j] > 0 {
+				value, err = reader.read(widths[j])
+				if err != nil {
+					return nil, nil, err
+				}
+				groupMissing = isMissing(value, widths[j])
+			} else {
+				groupMissing = isMissing(uint64(references[j]), bitsPerValue)
+			}
+
+			packed = append(packed, int64(references[j])+int64(value))
+			if missing != nil {
+				missing = append(missing, groupMissing)
+			}
+		}
+	}
+
+	if spatialDifferencing {
+		undoSpatialDifferencing(packed, missing, order, firstValues, minimum)
+	}
+
+	return packed, missing, nil
+}
+
+// undoSpatialDifferencing rebuilds the values from their differences, missing values are left out of the sequence
+func undoSpatialDifferencing(packed []int64, missing []bool, order int, firstValues []int64, minimum int64) {
+	previous := make([]int64, 0, len(packed))
+
+	for i := range packed {
+		if missing != nil && missing[i] {
+			continue
+		}
+
+		n := len(previous)
+		switch {
+		case n < order:
+			packed[i] = firstValues[n]
+		case order == 1:
+			packed[i] = packed[i] + minimum + previous[n-1]
+		default:
+			packed[i] = packed[i] + minimum + 2*previous[n-1] - previous[n-2]
+		}
+		previous = append(previous, packed[i])
+	}
+}
+
+// bitReader reads big endian integers of any number of bits
+type bitReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *bitReader) read(bits int) (uint64, error) {
+	if r.offset+bits > len(r.data)*8 {
+		return 0, fmt.Errorf("%w: data section is too short", ErrCorruptMessage)
+	}
+
+	var value uint64
+	for bits > 0 {
+		available := 8 - r.offset%8
+		taken := min(available, bits)
+		chunk := uint64(r.data[r.offset/8]>>(available-taken)) & (1<<taken - 1)
+
+		value = value<<taken | chunk
+		bits -= taken
+		r.offset += taken
+	}
+
+	return value, nil
+}
+
+// readSigned reads an integer whose first bit is the sign
+func (r *bitReader) readSigned(bits int) (int64, error) {
+	if bits == 0 {
+		return 0, nil
+	}
+
+	value, err := r.read(bits)
+	if err != nil {
+		return 0, err
+	}
+
+	magnitude := int64(value & (1<<(bits-1) - 1))
+	if value>>(bits-1) == 1 {
+		return -magnitude, nil
+	}
+
+	return magnitude, nil
+}
+
+// align moves to the start of the next byte
+func (r *bitReader) align() {
+	r.offset = (r.offset + 7) / 8 * 8
+}
+
+func uint16At(section []byte, index int) uint16 {
+	return binary.BigEndian.Uint16(section[index:])
+}
+
+func uint32At(section []byte, index int) uint32 {
+	return binary.BigEndian.Uint32(section[index:])
+}
+
+// int16At and int32At read signed values, which GRIB2 stores with a sign bit instead of two's complement
+func int16At(section []byte, index int) int {
+	value := uint16At(section, index)
+	if value&0x8000 != 0 {
+		return -int(value & 0x7fff)
+	}
+
+	return int(value)
+}
+
+func int32At(section []byte, index int) int {
+	value := uint32At(section, index)
+	if value&0x80000000 != 0 {
+		return -int(value & 0x7fffffff)
+	}
+
+	return int(value)
+}
+
+// decodeGrid unpacks a message into a grid
+func decodeGrid(message *grib2Message) (*geometry.Grid, error) {
+	definition, err := message.gridDefinition()
+	if err != nil {
+		return nil, err
+	}
+
+	values, missing, err := message.values(definition.Len())
+	if err != nil {
+		return nil, err
+	}
+
+	return &geometry.Grid{GridDefinition: definition, Values: values, Missing: missing}, nil
+}
+
+// decodeWindow unpacks the points of a message covering bounds. Only simple packing is read point by point,
+// with complex packing the whole grid is decoded and then cropped.
+func decodeWindow(message *grib2Message, bounds geometry.BoundingBox) (*geometry.Grid, error) {
+	definition, err := message.gridDefinition()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := message.packing(definition.Len())
+	if err != nil {
+		return nil, err
+	}
+	if p.template != 0 {
+		grid, err := decodeGrid(message)
+		if err != nil {
+			return nil, err
+		}
+		return grid.Crop(bounds), nil
+	}
+
+	subGrid, x0, y0 := definition.SubGrid(bounds)
+	values, missing, err := message.simpleWindow(p, definition, subGrid, x0, y0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &geometry.Grid{GridDefinition: subGrid, Values: values, Missing: missing}, nil
+}
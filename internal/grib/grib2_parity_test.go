@@ -0,0 +1,89 @@
+//go:build !purego
+
+package grib
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGoDecoderMatchesEcCodes compares both decoders on real files, the AROME files put in testdata
+// (CI downloads some) or any file given with
+// GRIB_PARITY_FILE=tmp/file_SP1_2025-06-19T06:00:00Z_01.grib2 go test ./internal/grib
+func TestGoDecoderMatchesEcCodes(t *testing.T) {
+	filenames, err := filepath.Glob("testdata/*.grib2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename := os.Getenv("GRIB_PARITY_FILE"); filename != "" {
+		filenames = []string{filename}
+	}
+	if len(filenames) == 0 {
+		t.Skip("no GRIB file in testdata and GRIB_PARITY_FILE is not set")
+	}
+
+	for _, filename := range filenames {
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			compareDecoders(t, filename)
+		})
+	}
+}
+
+func compareDecoders(t *testing.T, filename string) {
+	reader, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for reader.Next() {
+		message := reader.Message()
+
+		parsed, err := parseGrib2(message.data)
+		if err == nil {
+			var expected Metadata
+			var metadata Metadata
+			expected, err = message.Metadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata, err = parsed.metadata()
+			if err == nil && metadata != expected {
+				t.Errorf("message at offset %d: metadata = %+v; ecCodes says %+v", message.Offset, metadata, expected)
+			}
+		}
+		if errors.Is(err, ErrUnsupportedTemplate) || errors.Is(err, ErrUnsupportedGrid) {
+			t.Logf("message at offset %d: %v", message.Offset, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("message at offset %d: %v", message.Offset, err)
+		}
+
+		expected, err := message.Grid(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		grid, err := decodeGrid(parsed)
+		if err != nil {
+			t.Fatalf("message at offset %d: %v", message.Offset, err)
+		}
+
+		if grid.GridDefinition != expected.GridDefinition {
+			t.Errorf("message at offset %d: grid = %+v; ecCodes says %+v", message.Offset, grid.GridDefinition, expected.GridDefinition)
+			continue
+		}
+		for i := range grid.Values {
+			if grid.IsMissing(i) != expected.IsMissing(i) || math.Abs(float64(grid.Values[i]-expected.Values[i])) > 1e-6*math.Abs(float64(expected.Values[i])) {
+				t.Errorf("message at offset %d: value %d = %v; ecCodes says %v", message.Offset, i, grid.Values[i], expected.Values[i])
+				break
+			}
+		}
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,152 @@
+package grib
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// parameter names a GRIB2 parameter the way ecCodes does, ecCodes tells apart some parameters by their level
+// or step type (2t is the temperature at 2 metres, t at any other level), empty keys and a level of -1 match anything
+type parameter struct {
+	discipline  int
+	category    int
+	number      int
+	typeOfLevel string
+	level       int
+	stepType    string
+
+	shortName string
+	name      string
+	units     string
+}
+
+// parameters are the fields of the AROME packages, other fields get the "unknown" shortName.
+// More specific entries come first.
+var parameters = []parameter{
+	{0, 0, 0, "heightAboveGround", 2, "", "2t", "2 metre temperature", "K"},
+	{0, 0, 0, "", -1, "", "t", "Temperature", "K"},
+	{0, 0, 6, "heightAboveGround", 2, "", "2d", "2 metre dewpoint temperature", "K"},
+	{0, 0, 6, "", -1, "", "dpt", "Dew point temperature", "K"},
+	{0, 1, 1, "heightAboveGround", 2, "", "2r", "2 metre relative humidity", "%"},
+	{0, 1, 1, "", -1, "", "r", "Relative humidity", "%"},
+	{0, 1, 8, "", -1, "accum", "tp", "Total precipitation", "kg m**-2"},
+	{0, 1, 65, "", -1, "accum", "tirf", "Time integral of rain flux", "kg m**-2"},
+	{0, 2, 0, "heightAboveGround", 10, "", "10wdir", "10 metre wind direction", "Degree true"},
+	{0, 2, 0, "", -1, "", "wdir", "Wind direction", "Degree true"},
+	{0, 2, 1, "heightAboveGround", 10, "", "10si", "10 metre wind speed", "m s**-1"},
+	{0, 2, 1, "", -1, "", "ws", "Wind speed", "m s**-1"},
+	{0, 2, 2, "heightAboveGround", 10, "", "10u", "10 metre U wind component", "m s**-1"},
+	{0, 2, 2, "", -1, "", "u", "U component of wind", "m s**-1"},
+	{0, 2, 3, "heightAboveGround", 10, "", "10v", "10 metre V wind component", "m s**-1"},
+	{0, 2, 3, "", -1, "", "v", "V component of wind", "m s**-1"},
+	{0, 2, 22, "heightAboveGround", 10, "max", "10fg", "Maximum 10 metre wind gust since previous post-processing", "m s**-1"},
+	{0, 2, 22, "heightAboveGround", 10, "instant", "i10fg", "Instantaneous 10 metre wind gust", "m s**-1"},
+	{0, 3, 0, "surface", -1, "", "sp", "Surface pressure", "Pa"},
+	{0, 3, 1, "", -1, "", "prmsl", "Pressure reduced to MSL", "Pa"},
+	{0, 6, 1, "", -1, "", "tcc", "Total cloud cover", "%"},
+	{0, 6, 3, "", -1, "", "lcc", "Low cloud cover", "%"},
+	{0, 6, 4, "", -1, "", "mcc", "Medium cloud cover", "%"},
+	{0, 6, 5, "", -1, "", "hcc", "High cloud cover", "%"},
+}
+
+func lookupParameter(discipline int, category int, number int, typeOfLevel string, level int, stepType string) parameter {
+	for _, p := range parameters {
+		if p.discipline == discipline && p.category == category && p.number == number &&
+			(p.typeOfLevel == "" || p.typeOfLevel == typeOfLevel) &&
+			(p.level == -1 || p.level == level) &&
+			(p.stepType == "" || p.stepType == stepType) {
+			return p
+		}
+	}
+
+	return parameter{shortName: "unknown", name: "unknown", units: "unknown"}
+}
+
+// levelTypes are the names ecCodes gives to the types of fixed surfaces (code table 4.5)
+var levelTypes = map[byte]string{
+	1:   "surface",
+	8:   "nominalTop",
+	10:  "entireAtmosphere",
+	100: "isobaricInhPa",
+	101: "meanSea",
+	102: "heightAboveSea",
+	103: "heightAboveGround",
+	105: "hybrid",
+	106: "depthBelowLandLayer",
+}
+
+// surface returns the type of level and the level of the first fixed surface
+func surface(surfaceType byte, scaleFactor byte, scaledValue uint32) (string, int) {
+	typeOfLevel, exists := levelTypes[surfaceType]
+	if !exists {
+		typeOfLevel = "unknown"
+	}
+
+	if scaledValue == math.MaxUint32 {
+		return typeOfLevel, 0
+	}
+
+	// The scale factor is signed with a sign bit
+	scale := int(scaleFactor & 0x7f)
+	if scaleFactor&0x80 != 0 {
+		scale = -scale
+	}
+	level := float64(scaledValue) / math.Pow(10, float64(scale))
+
+	// Isobaric levels are given in Pa but named in hPa
+	if surfaceType == 100 {
+		level /= 100
+	}
+
+	return typeOfLevel, int(math.Round(level))
+}
+
+// timeRangeUnit is the duration of the unit of time ranges (code table 4.4)
+func timeRangeUnit(unit byte) (time.Duration, error) {
+	switch unit {
+	case 0:
+		return time.Minute, nil
+	case 1:
+		return time.Hour, nil
+	case 2:
+		return 24 * time.Hour, nil
+	case 10:
+		return 3 * time.Hour, nil
+	case 11:
+		return 6 * time.Hour, nil
+	case 12:
+		return 12 * time.Hour, nil
+	case 13:
+		return time.Second, nil
+	default:
+		return 0, fmt.Errorf("%w: time range unit %d", ErrUnsupportedTemplate, unit)
+	}
+}
+
+// formatStep writes steps in hours like ecCodes, or in minutes when they aren't whole hours
+func formatStep(step time.Duration) string {
+	if step%time.Hour == 0 {
+		return fmt.Sprintf("%d", int(step/time.Hour))
+	}
+
+	return fmt.Sprintf("%dm", int(step/time.Minute))
+}
+
+// statisticalStepType names the type of statistical processing (code table 4.10)
+func statisticalStepType(processing byte) string {
+	switch processing {
+	case 0:
+		return "avg"
+	case 1:
+		return "accum"
+	case 2:
+		return "max"
+	case 3:
+		return "min"
+	case 4:
+		return "diff"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,415 @@
+package grib
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// testField is a field encoded into a synthetic GRIB2 message on a 3x2 grid from 40N 359E (-1) with 0.5 degree steps
+type testField struct {
+	category    byte
+	number      byte
+	surfaceType byte
+	level       uint32
+	// accumulated makes an accumulation over the last hour (template 4.8) instead of an instantaneous value (template 4.0)
+	accumulated bool
+	step        uint32
+	// values are written with decimalScale decimals, NaN values are left out by a bitmap
+	values       []float64
+	decimalScale int
+	// packing is the data representation template: 0 for simple, 2 for complex, 3 for complex with spatial differencing
+	packing int
+}
+
+func section(number byte, content ...[]byte) []byte {
+	body := []byte{number}
+	for _, part := range content {
+		body = append(body, part...)
+	}
+
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(body)+4)), body...)
+}
+
+func u16(value int) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(value))
+}
+
+func u32(value uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, value)
+}
+
+// bitWriter is the counterpart of bitReader
+type bitWriter struct {
+	data   []byte
+	offset int
+}
+
+func (w *bitWriter) write(value uint64, count int) {
+	for i := count - 1; i >= 0; i-- {
+		if w.offset%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if value>>i&1 == 1 {
+			w.data[len(w.data)-1] |= 0x80 >> (w.offset % 8)
+		}
+		w.offset++
+	}
+}
+
+func (w *bitWriter) writeSigned(value int64, count int) {
+	sign := uint64(0)
+	if value < 0 {
+		sign, value = 1, -value
+	}
+	w.write(sign, 1)
+	w.write(uint64(value), count-1)
+}
+
+func (w *bitWriter) align() {
+	w.offset = len(w.data) * 8
+}
+
+func bitsFor(value int64) int {
+	return bits.Len64(uint64(value))
+}
+
+// encode builds the message the way AROME files are laid out
+func (f testField) encode() []byte {
+	identification := section(1, u16(85), u16(0), []byte{2, 0, 1}, u16(2025), []byte{6, 19, 6, 0, 0, 0, 1})
+
+	grid := section(3,
+		[]byte{0}, u32(6), []byte{0, 0}, u16(0),
+		[]byte{6}, make([]byte, 15),
+		u32(3), u32(2), u32(0), u32(math.MaxUint32),
+		u32(40000000), u32(359000000), []byte{48},
+		u32(39500000), u32(0),
+		u32(500000), u32(500000),
+		[]byte{0},
+	)
+
+	template := 0
+	if f.accumulated {
+		template = 8
+	}
+	product := []byte{}
+	product = append(product, u16(0)...)
+	product = append(product, u16(template)...)
+	product = append(product, f.category, f.number, 2, 0, 0, 0, 0, 0, 1)
+	if f.accumulated {
+		product = append(product, u32(f.step-1)...)
+	} else {
+		product = append(product, u32(f.step)...)
+	}
+	product = append(product, f.surfaceType, 0)
+	product = append(product, u32(f.level)...)
+	product = append(product, 255, 0)
+	product = append(product, u32(math.MaxUint32)...)
+	if f.accumulated {
+		product = append(product, u16(2025)...)
+		product = append(product, 6, 19, byte(6+f.step), 0, 0, 1)
+		product = append(product, u32(0)...)
+		product = append(product, 1, 2, 1)
+		product = append(product, u32(1)...)
+		product = append(product, 1)
+		product = append(product, u32(0)...)
+	}
+
+	// Values are scaled to integers, the reference value is their minimum
+	bitmap := []byte{255}
+	present := bitWriter{}
+	integers := []int64{}
+	for _, value := range f.values {
+		if math.IsNaN(value) {
+			present.write(0, 1)
+			continue
+		}
+
+		present.write(1, 1)
+		integers = append(integers, int64(math.Round(value*math.Pow(10, float64(f.decimalScale)))))
+	}
+	if len(integers) != len(f.values) {
+		bitmap = append([]byte{0}, present.data...)
+	}
+
+	reference := integers[0]
+	for _, integer := range integers {
+		reference = min(reference, integer)
+	}
+
+	var representation, data []byte
+	switch f.packing {
+	case 0:
+		packed := bitWriter{}
+		width := 0
+		for _, integer := range integers {
+			width = max(width, bitsFor(integer-reference))
+		}
+		for _, integer := range integers {
+			packed.write(uint64(integer-reference), width)
+		}
+
+		representation = append(u32(uint32(len(integers))), u16(0)...)
+		representation = append(representation, u32(math.Float32bits(float32(reference)))...)
+		representation = append(representation, u16(0)...)
+		representation = append(representation, u16(f.decimalScale)...)
+		representation = append(representation, byte(width), 0)
+		data = packed.data
+
+	default:
+		packed := bitWriter{}
+		values := integers
+		var firstValues []int64
+		minimum := int64(0)
+
+		if f.packing == 3 {
+			// Second order differences, the first two values are given apart
+			firstValues = integers[:2]
+			values = make([]int64, len(integers))
+			for n := 2; n < len(integers); n++ {
+				values[n] = integers[n] - 2*integers[n-1] + integers[n-2]
+			}
+			minimum = values[2]
+			for _, value := range values[2:] {
+				minimum = min(minimum, value)
+			}
+			for n := range values {
+				if n >= 2 {
+					values[n] -= minimum
+				}
+			}
+
+			for _, value := range firstValues {
+				packed.writeSigned(value, 16)
+			}
+			packed.writeSigned(minimum, 16)
+		} else {
+			values = make([]int64, len(integers))
+			for n, integer := range integers {
+				values[n] = integer - reference
+			}
+		}
+
+		// Groups of 2 values
+		groupReferences, groupWidths := []int64{}, []int64{}
+		for n := 0; n < len(values); n += 2 {
+			group := values[n:min(n+2, len(values))]
+			groupReference := min(group[0], group[len(group)-1])
+			groupReferences = append(groupReferences, groupReference)
+			groupWidths = append(groupWidths, int64(bitsFor(max(group[0], group[len(group)-1])-groupReference)))
+		}
+
+		referenceBits, widthBits := 0, 0
+		for n := range groupReferences {
+			referenceBits = max(referenceBits, bitsFor(groupReferences[n]))
+			widthBits = max(widthBits, bitsFor(groupWidths[n]))
+		}
+
+		for _, groupReference := range groupReferences {
+			packed.write(uint64(groupReference), referenceBits)
+		}
+		packed.align()
+		for _, width := range groupWidths {
+			packed.write(uint64(width), widthBits)
+		}
+		packed.align()
+		// Group lengths take 0 bits since they are all the reference length
+		for n := 0; n < len(values); n += 2 {
+			for _, value := range values[n:min(n+2, len(values))] {
+				packed.write(uint64(value-groupReferences[n/2]), int(groupWidths[n/2]))
+			}
+		}
+
+		packedReference := float32(reference)
+		if f.packing == 3 {
+			packedReference = 0
+		}
+
+		representation = append(u32(uint32(len(integers))), u16(f.packing)...)
+		representation = append(representation, u32(math.Float32bits(packedReference))...)
+		representation = append(representation, u16(0)...)
+		representation = append(representation, u16(f.decimalScale)...)
+		representation = append(representation, byte(referenceBits), 0)
+		representation = append(representation, 1, 0)
+		representation = append(representation, u32(math.MaxUint32)...)
+		representation = append(representation, u32(math.MaxUint32)...)
+		representation = append(representation, u32(uint32(len(groupReferences)))...)
+		representation = append(representation, 0, byte(widthBits))
+		representation = append(representation, u32(2)...)
+		representation = append(representation, 1)
+		representation = append(representation, u32(uint32(2-len(values)%2))...)
+		representation = append(representation, 0)
+		if f.packing == 3 {
+			representation = append(representation, 2, 2)
+		}
+		data = packed.data
+	}
+
+	body := append(identification, grid...)
+	body = append(body, section(4, product)...)
+	body = append(body, section(5, representation)...)
+	body = append(body, section(6, bitmap)...)
+	body = append(body, section(7, data)...)
+
+	message := []byte("GRIB\x00\x00\x00\x02")
+	message = binary.BigEndian.AppendUint64(message, uint64(INDICATOR_SECTION_LENGTH+len(body)+4))
+	message = append(message, body...)
+
+	return append(message, "7777"...)
+}
+
+func TestDecodeGrib2(t *testing.T) {
+	temperatures := []float64{293.15, 293.4, 292.85, 291.5, 290.05, 288.95}
+
+	testCases := []struct {
+		name             string
+		field            testField
+		expectedMetadata Metadata
+	}{
+		{
+			name:  "Simple packing",
+			field: testField{category: 0, number: 0, surfaceType: 103, level: 2, step: 3, values: temperatures, decimalScale: 2, packing: 0},
+			expectedMetadata: Metadata{
+				ShortName: "2t", Name: "2 metre temperature", TypeOfLevel: "heightAboveGround", Level: 2, StepRange: "3", StepType: "instant", Units: "K",
+				ValidityTime: time.Date(2025, 6, 19, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Complex packing",
+			field: testField{category: 0, number: 0, surfaceType: 100, level: 85000, step: 1, values: temperatures, decimalScale: 2, packing: 2},
+			expectedMetadata: Metadata{
+				ShortName: "t", Name: "Temperature", TypeOfLevel: "isobaricInhPa", Level: 850, StepRange: "1", StepType: "instant", Units: "K",
+				ValidityTime: time.Date(2025, 6, 19, 7, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Complex packing with spatial differencing",
+			field: testField{category: 2, number: 2, surfaceType: 103, level: 10, step: 12, values: []float64{-3.5, -2.25, 0, 1.75, 4.5, 3.25}, decimalScale: 2, packing: 3},
+			expectedMetadata: Metadata{
+				ShortName: "10u", Name: "10 metre U wind component", TypeOfLevel: "heightAboveGround", Level: 10, StepRange: "12", StepType: "instant", Units: "m s**-1",
+				ValidityTime: time.Date(2025, 6, 19, 18, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Accumulation with a bitmap",
+			field: testField{category: 1, number: 65, surfaceType: 1, accumulated: true, step: 3, values: []float64{0, 0.4, math.NaN(), 12.8, 0, 3}, decimalScale: 1, packing: 0},
+			expectedMetadata: Metadata{
+				ShortName: "tirf", Name: "Time integral of rain flux", TypeOfLevel: "surface", Level: 0, StepRange: "2-3", StepType: "accum", Units: "kg m**-2",
+				ValidityTime: time.Date(2025, 6, 19, 9, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message, err := parseGrib2(tc.field.encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, err := message.metadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata != tc.expectedMetadata {
+				t.Errorf("metadata() = %+v; want %+v", metadata, tc.expectedMetadata)
+			}
+
+			grid, err := decodeGrid(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if grid.Nx != 3 || grid.Ny != 2 || grid.FirstLat != 40 || grid.FirstLon != 359 || grid.DLat != -0.5 || grid.DLon != 0.5 {
+				t.Errorf("grid definition = %+v; want 3x2 from 40N 359E with steps of -0.5 and 0.5", grid.GridDefinition)
+			}
+
+			for i, expected := range tc.field.values {
+				if math.IsNaN(expected) {
+					if !grid.IsMissing(i) || grid.Values[i] != MISSING_VALUE {
+						t.Errorf("value %d = %v (missing %v); want missing", i, grid.Values[i], grid.IsMissing(i))
+					}
+					continue
+				}
+
+				if grid.IsMissing(i) || math.Abs(float64(grid.Values[i])-expected) > 1e-4 {
+					t.Errorf("value %d = %v (missing %v); want %v", i, grid.Values[i], grid.IsMissing(i), expected)
+				}
+			}
+
+			// The two eastern columns, with the missing point of the bitmap and points after it
+			bounds := geometry.BoundingBox{MinLat: 39.5, MinLon: -0.5, MaxLat: 40, MaxLon: 0}
+			window, err := decodeWindow(message, bounds)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cropped := grid.Crop(bounds)
+			if window.GridDefinition != cropped.GridDefinition || window.Nx != 2 || window.Ny != 2 {
+				t.Fatalf("window = %+v; want %+v", window.GridDefinition, cropped.GridDefinition)
+			}
+			for i := range cropped.Values {
+				if window.Values[i] != cropped.Values[i] || window.IsMissing(i) != cropped.IsMissing(i) {
+					t.Errorf("window value %d = %v (missing %v); want %v (missing %v)", i, window.Values[i], window.IsMissing(i), cropped.Values[i], cropped.IsMissing(i))
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeGrib2Errors(t *testing.T) {
+	valid := testField{category: 0, number: 0, surfaceType: 103, level: 2, values: []float64{1, 2, 3, 4, 5, 6}}.encode()
+
+	jpeg := append([]byte{}, valid...)
+	// Octets 10-11 of section 5 give the data representation template, which follows sections 0, 1, 3 and 4
+	representation := INDICATOR_SECTION_LENGTH + 21 + 72 + 34
+	binary.BigEndian.PutUint16(jpeg[representation+9:], 40)
+
+	truncated := append([]byte{}, valid[:len(valid)-6]...)
+	truncated = append(truncated, "7777"...)
+
+	// 6 values of 60 bits don't fit in the data section
+	wideValues := append([]byte{}, valid...)
+	wideValues[representation+19] = 60
+
+	// 65536x65536 points overflow 32 bits, the grid definition follows sections 0 and 1
+	hugeGrid := append([]byte{}, valid...)
+	grid := INDICATOR_SECTION_LENGTH + 21
+	binary.BigEndian.PutUint32(hugeGrid[grid+6:], 0)
+	binary.BigEndian.PutUint32(hugeGrid[grid+30:], 65536)
+	binary.BigEndian.PutUint32(hugeGrid[grid+34:], 65536)
+
+	// Octets 32-35 of section 5 give the number of groups of complex packing
+	manyGroups := testField{category: 0, number: 0, surfaceType: 103, level: 2, values: []float64{1, 2, 3, 4, 5, 6}, packing: 2}.encode()
+	binary.BigEndian.PutUint32(manyGroups[representation+31:], 1<<30)
+
+	testCases := []struct {
+		name     string
+		message  []byte
+		expected error
+	}{
+		{name: "JPEG 2000 packing", message: jpeg, expected: ErrUnsupportedTemplate},
+		{name: "Truncated data section", message: truncated, expected: ErrCorruptMessage},
+		{name: "Values larger than the data section", message: wideValues, expected: ErrCorruptMessage},
+		{name: "Number of points overflowing", message: hugeGrid, expected: ErrCorruptMessage},
+		{name: "More groups than values", message: manyGroups, expected: ErrCorruptMessage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message, err := parseGrib2(tc.message)
+			if err == nil {
+				_, err = decodeGrid(message)
+			}
+
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("decoding = %v; want %v", err, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package grib
+
+import (
+	"math"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// InventoryEntry describes a message of a GRIB file, see Inventory
+type InventoryEntry struct {
+	Offset      int64       `json:"offset"`
+	ShortName   string      `json:"short_name"`
+	Name        string      `json:"name"`
+	TypeOfLevel string      `json:"type_of_level"`
+	Level       int         `json:"level"`
+	StepRange   string      `json:"step_range"`
+	Units       string      `json:"units"`
+	Nx          int         `json:"nx"`
+	Ny          int         `json:"ny"`
+	Stats       *ValueStats `json:"stats,omitempty"`
+	// Error tells why a message couldn't be decoded, the other messages are listed anyway
+	Error string `json:"error,omitempty"`
+}
+
+// ValueStats summarizes the values of a grid, missing values are left out
+type ValueStats struct {
+	Count   int     `json:"count"`
+	Missing int     `json:"missing"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Mean    float64 `json:"mean"`
+}
+
+// Inventory lists the messages of a GRIB file with statistics on their values, to see what a package
+// contains without outside tools. Messages that can't be decoded get an Error, only a damaged file fails.
+func Inventory(filename string) ([]InventoryEntry, error) {
+	reader, err := Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	entries := []InventoryEntry{}
+	for reader.Next() {
+		message := reader.Message()
+		entry := InventoryEntry{Offset: message.Offset}
+
+		metadata, err := message.Metadata()
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+		entry.ShortName = metadata.ShortName
+		entry.Name = metadata.Name
+		entry.TypeOfLevel = metadata.TypeOfLevel
+		entry.Level = metadata.Level
+		entry.StepRange = metadata.StepRange
+		entry.Units = metadata.Units
+
+		grid, err := message.Grid(nil)
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+		entry.Nx = grid.Nx
+		entry.Ny = grid.Ny
+		entry.Stats = gridStats(grid)
+
+		entries = append(entries, entry)
+	}
+	if err := reader.Err(); err != nil {
+		return entries, err
+	}
+
+	return entries, nil
+}
+
+// gridStats returns the statistics of the values of a grid, min, max and mean are 0 when they are all missing
+func gridStats(grid *geometry.Grid) *ValueStats {
+	stats := &ValueStats{Min: math.Inf(1), Max: math.Inf(-1)}
+
+	sum := 0.0
+	for i, value := range grid.Values {
+		if grid.IsMissing(i) || math.IsNaN(float64(value)) {
+			stats.Missing++
+			continue
+		}
+
+		stats.Count++
+		stats.Min = math.Min(stats.Min, float64(value))
+		stats.Max = math.Max(stats.Max, float64(value))
+		sum += float64(value)
+	}
+
+	if stats.Count == 0 {
+		stats.Min, stats.Max = 0, 0
+		return stats
+	}
+	stats.Mean = sum / float64(stats.Count)
+
+	return stats
+}
@@ -0,0 +1,34 @@
+package grib
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+func TestGridStats(t *testing.T) {
+	nan := float32(math.NaN())
+
+	tests := []struct {
+		name    string
+		values  []float32
+		missing []bool
+		want    ValueStats
+	}{
+		{"every value", []float32{1, 2, 6}, nil, ValueStats{Count: 3, Min: 1, Max: 6, Mean: 3}},
+		{"bitmap", []float32{1, 9999, 5}, []bool{false, true, false}, ValueStats{Count: 2, Missing: 1, Min: 1, Max: 5, Mean: 3}},
+		{"NaN", []float32{nan, -4}, nil, ValueStats{Count: 1, Missing: 1, Min: -4, Max: -4, Mean: -4}},
+		{"nothing but missing values", []float32{9999, 9999}, []bool{true, true}, ValueStats{Missing: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := &geometry.Grid{GridDefinition: geometry.GridDefinition{Nx: len(tt.values), Ny: 1}, Values: tt.values, Missing: tt.missing}
+
+			if got := gridStats(grid); *got != tt.want {
+				t.Errorf("gridStats() = %+v; want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+//go:build !purego
+
+package grib
+
+/*
+#cgo pkg-config: eccodes
+#include <eccodes.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// Message is a single field of a GRIB file, it is only decoded by ecCodes when its metadata or values are asked for
+type Message struct {
+	// Offset is the position of the message in the file
+	Offset  int64
+	Edition int
+
+	data     []byte
+	handle   *C.codes_handle
+	metadata *Metadata
+}
+
+// Metadata describes what a message contains
+type Metadata struct {
+	ShortName   string
+	Name        string
+	TypeOfLevel string
+	Level       int
+	StepRange   string
+	StepType    string
+	Units       string
+	// ValidityTime is the time the forecast is for, in UTC
+	ValidityTime time.Time
+}
+
+func newMessage(offset int64, edition int, data []byte) *Message {
+	return &Message{Offset: offset, Edition: edition, data: data}
+}
+
+// Close releases the memory held by ecCodes, Reader does it when moving to the next message
+func (m *Message) Close() {
+	if m.handle != nil {
+		C.codes_handle_delete(m.handle)
+		m.handle = nil
+	}
+	m.data = nil
+}
+
+func (m *Message) openHandle() (*C.codes_handle, error) {
+	if m.handle != nil {
+		return m.handle, nil
+	}
+	if len(m.data) == 0 {
+		return nil, fmt.Errorf("GRIB message at offset %d is closed", m.Offset)
+	}
+
+	// The copy belongs to ecCodes so it stays valid whatever the Go garbage collector does
+	m.handle = C.codes_handle_new_from_message_copy(nil, unsafe.Pointer(&m.data[0]), C.size_t(len(m.data)))
+	if m.handle == nil {
+		return nil, fmt.Errorf("%w: ecCodes can't decode the message at offset %d", ErrCorruptMessage, m.Offset)
+	}
+
+	return m.handle, nil
+}
+
+// Metadata decodes the header of the message, keys a message doesn't have are left empty
+func (m *Message) Metadata() (Metadata, error) {
+	if m.metadata != nil {
+		return *m.metadata, nil
+	}
+
+	handle, err := m.openHandle()
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	metadata := Metadata{
+		ShortName:   getString(handle, "shortName"),
+		Name:        getString(handle, "name"),
+		TypeOfLevel: getString(handle, "typeOfLevel"),
+		Level:       int(getLong(handle, "level")),
+		StepRange:   getString(handle, "stepRange"),
+		StepType:    getString(handle, "stepType"),
+		Units:       getString(handle, "units"),
+	}
+
+	// validityDate is like 20250601 and validityTime like 1300
+	date, hourMinute := getLong(handle, "validityDate"), getLong(handle, "validityTime")
+	if date > 0 {
+		metadata.ValidityTime = time.Date(int(date/10000), time.Month(date/100%100), int(date%100), int(hourMinute/100), int(hourMinute%100), 0, 0, time.UTC)
+	}
+
+	m.metadata = &metadata
+
+	return metadata, nil
+}
+
+// Grid decodes the values of the message, or only the ones covering bounds when they aren't nil
+func (m *Message) Grid(bounds *geometry.BoundingBox) (*geometry.Grid, error) {
+	handle, err := m.openHandle()
+	if err != nil {
+		return nil, err
+	}
+
+	grid, err := readGrid(handle, bounds)
+	if err != nil {
+		return nil, fmt.Errorf("message at offset %d: %w", m.Offset, err)
+	}
+
+	return grid, nil
+}
+
+func getString(handle *C.codes_handle, key string) string {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+
+	var length C.size_t
+	if C.codes_get_length(handle, cKey, &length) != 0 || length == 0 {
+		return ""
+	}
+
+	buffer := (*C.char)(C.malloc(length))
+	defer C.free(unsafe.Pointer(buffer))
+
+	if C.codes_get_string(handle, cKey, buffer, &length) != 0 {
+		return ""
+	}
+
+	return C.GoString(buffer)
+}
+
+func getLong(handle *C.codes_handle, key string) int64 {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+
+	var value C.long
+	if C.codes_get_long(handle, cKey, &value) != 0 {
+		return 0
+	}
+
+	return int64(value)
+}
@@ -0,0 +1,103 @@
+//go:build purego
+
+package grib
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Michaelvilleneuve/weather-fetch-go/internal/geometry"
+)
+
+// Message is a single field of a GRIB file, it is only decoded when its metadata or values are asked for.
+// This build decodes GRIB2 in Go (see grib2.go) instead of calling ecCodes.
+type Message struct {
+	// Offset is the position of the message in the file
+	Offset  int64
+	Edition int
+
+	data     []byte
+	parsed   *grib2Message
+	metadata *Metadata
+}
+
+// Metadata describes what a message contains
+type Metadata struct {
+	ShortName   string
+	Name        string
+	TypeOfLevel string
+	Level       int
+	StepRange   string
+	StepType    string
+	Units       string
+	// ValidityTime is the time the forecast is for, in UTC
+	ValidityTime time.Time
+}
+
+func newMessage(offset int64, edition int, data []byte) *Message {
+	return &Message{Offset: offset, Edition: edition, data: data}
+}
+
+// Close releases the message, Reader does it when moving to the next message
+func (m *Message) Close() {
+	m.data = nil
+	m.parsed = nil
+}
+
+func (m *Message) parse() (*grib2Message, error) {
+	if m.parsed != nil {
+		return m.parsed, nil
+	}
+	if len(m.data) == 0 {
+		return nil, fmt.Errorf("GRIB message at offset %d is closed", m.Offset)
+	}
+
+	parsed, err := parseGrib2(m.data)
+	if err != nil {
+		return nil, fmt.Errorf("message at offset %d: %w", m.Offset, err)
+	}
+	m.parsed = parsed
+
+	return parsed, nil
+}
+
+// Metadata decodes the header of the message
+func (m *Message) Metadata() (Metadata, error) {
+	if m.metadata != nil {
+		return *m.metadata, nil
+	}
+
+	parsed, err := m.parse()
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	metadata, err := parsed.metadata()
+	if err != nil {
+		return Metadata{}, fmt.Errorf("message at offset %d: %w", m.Offset, err)
+	}
+	m.metadata = &metadata
+
+	return metadata, nil
+}
+
+// Grid decodes the values of the message, or only the ones covering bounds when they aren't nil.
+// Only messages with simple packing skip the values outside bounds, see decodeWindow.
+func (m *Message) Grid(bounds *geometry.BoundingBox) (*geometry.Grid, error) {
+	parsed, err := m.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var grid *geometry.Grid
+	if bounds != nil {
+		grid, err = decodeWindow(parsed, *bounds)
+	} else {
+		grid, err = decodeGrid(parsed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("message at offset %d: %w", m.Offset, err)
+	}
+
+	return grid, nil
+}
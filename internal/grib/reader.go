@@ -0,0 +1,165 @@
+package grib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// Section 0 holds "GRIB", the edition and the total length of the message
+	INDICATOR_SECTION_LENGTH = 16
+	// A message can't be larger than that, a bigger length means the file is corrupt
+	MAX_MESSAGE_LENGTH = 1 << 30
+	// READ_CHUNK_LENGTH is the most allocated for a message before its bytes are read, the buffer grows with them
+	READ_CHUNK_LENGTH = 1 << 20
+)
+
+// Reader reads the messages of a GRIB file one at a time, so that messages that aren't needed
+// are skipped without being decoded and the file is never loaded in memory as a whole.
+//
+//	reader, err := grib.Open(filename)
+//	...
+//	defer reader.Close()
+//	for reader.Next() {
+//		metadata, err := reader.Message().Metadata()
+//		...
+//	}
+//	if err := reader.Err(); err != nil {
+//		...
+//	}
+type Reader struct {
+	input   *bufio.Reader
+	closer  io.Closer
+	offset  int64
+	message *Message
+	err     error
+}
+
+// NewReader reads messages from r, it is up to the caller to close r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{input: bufio.NewReaderSize(r, 1<<20)}
+}
+
+// Open reads the messages of a file, the file is closed with the reader
+func Open(filename string) (*Reader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := NewReader(file)
+	reader.closer = file
+
+	return reader, nil
+}
+
+// Next moves to the next message, it returns false at the end of the input or on error, see Err.
+// The previous message is released and must not be used anymore.
+func (r *Reader) Next() bool {
+	r.releaseMessage()
+	if r.err != nil {
+		return false
+	}
+
+	offset, edition, data, err := r.readMessage()
+	if err != nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		return false
+	}
+
+	r.message = newMessage(offset, edition, data)
+
+	return true
+}
+
+// Message is the message read by the last call to Next
+func (r *Reader) Message() *Message {
+	return r.message
+}
+
+// Err is the first error met by Next, reaching the end of the input isn't an error
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Close releases the current message and closes the file opened by Open
+func (r *Reader) Close() error {
+	r.releaseMessage()
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+
+	return nil
+}
+
+func (r *Reader) releaseMessage() {
+	if r.message != nil {
+		r.message.Close()
+		r.message = nil
+	}
+}
+
+// readMessage reads the raw bytes of the next message using the length from its indicator section
+func (r *Reader) readMessage() (int64, int, []byte, error) {
+	// Skip anything before "GRIB", some files are padded between messages
+	for {
+		magic, err := r.input.Peek(4)
+		if err != nil {
+			// Less than 4 bytes left can't be a message
+			if errors.Is(err, io.EOF) {
+				return 0, 0, nil, io.EOF
+			}
+			return 0, 0, nil, err
+		}
+		if string(magic) == "GRIB" {
+			break
+		}
+
+		r.input.Discard(1)
+		r.offset++
+	}
+
+	offset := r.offset
+	indicator, err := r.input.Peek(INDICATOR_SECTION_LENGTH)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("%w: truncated at offset %d: %w", ErrCorruptMessage, offset, io.ErrUnexpectedEOF)
+	}
+
+	edition := int(indicator[7])
+	var length uint64
+	switch edition {
+	case 1:
+		length = uint64(indicator[4])<<16 | uint64(indicator[5])<<8 | uint64(indicator[6])
+	case 2:
+		length = binary.BigEndian.Uint64(indicator[8:16])
+	default:
+		return 0, 0, nil, fmt.Errorf("%w: unsupported edition %d at offset %d", ErrCorruptMessage, edition, offset)
+	}
+
+	if length < INDICATOR_SECTION_LENGTH || length > MAX_MESSAGE_LENGTH {
+		return 0, 0, nil, fmt.Errorf("%w: invalid length %d at offset %d", ErrCorruptMessage, length, offset)
+	}
+
+	// The buffer grows with the bytes actually read, a damaged length can't allocate more than what is left of the input
+	var buffer bytes.Buffer
+	buffer.Grow(int(min(length, READ_CHUNK_LENGTH)))
+	n, err := io.CopyN(&buffer, r.input, int64(length))
+	r.offset += n
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("%w: truncated at offset %d: %w", ErrCorruptMessage, offset, io.ErrUnexpectedEOF)
+	}
+	data := buffer.Bytes()
+
+	if !bytes.HasSuffix(data, []byte("7777")) {
+		return 0, 0, nil, fmt.Errorf("%w: message at offset %d doesn't end with 7777", ErrCorruptMessage, offset)
+	}
+
+	return offset, edition, data, nil
+}
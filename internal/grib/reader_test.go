@@ -0,0 +1,105 @@
+package grib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// fakeMessage builds a GRIB 2 message with a valid indicator section and end section around body
+func fakeMessage(body string) []byte {
+	length := INDICATOR_SECTION_LENGTH + len(body) + 4
+
+	message := []byte("GRIB\x00\x00\x00\x02")
+	message = binary.BigEndian.AppendUint64(message, uint64(length))
+	message = append(message, body...)
+
+	return append(message, "7777"...)
+}
+
+func TestReaderFraming(t *testing.T) {
+	first := fakeMessage("first")
+	second := fakeMessage("second message")
+
+	testCases := []struct {
+		name            string
+		input           []byte
+		expectedOffsets []int64
+		expectedError   bool
+	}{
+		{name: "Empty file", input: nil, expectedOffsets: nil},
+		{name: "Two messages", input: concat(first, second), expectedOffsets: []int64{0, int64(len(first))}},
+		{name: "Padding between messages", input: concat(first, []byte("\x00\x00\x00"), second, []byte("\n")), expectedOffsets: []int64{0, int64(len(first)) + 3}},
+		{name: "Truncated message", input: concat(first, second[:10]), expectedOffsets: []int64{0}, expectedError: true},
+		{name: "Missing end section", input: concat(first[:len(first)-4], []byte("0000")), expectedOffsets: nil, expectedError: true},
+		{name: "Unsupported edition", input: []byte("GRIB\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x14"), expectedOffsets: nil, expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := NewReader(bytes.NewReader(tc.input))
+			defer reader.Close()
+
+			var offsets []int64
+			for reader.Next() {
+				message := reader.Message()
+				if message.Edition != 2 {
+					t.Errorf("Edition = %d; want 2", message.Edition)
+				}
+				offsets = append(offsets, message.Offset)
+			}
+
+			if len(offsets) != len(tc.expectedOffsets) {
+				t.Fatalf("offsets = %v; want %v", offsets, tc.expectedOffsets)
+			}
+			for i := range offsets {
+				if offsets[i] != tc.expectedOffsets[i] {
+					t.Errorf("offsets = %v; want %v", offsets, tc.expectedOffsets)
+				}
+			}
+
+			if (reader.Err() != nil) != tc.expectedError {
+				t.Errorf("Err() = %v; want error %v", reader.Err(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestReaderTruncatedIsCorrupt(t *testing.T) {
+	message := fakeMessage("body")
+	reader := NewReader(bytes.NewReader(message[:len(message)-2]))
+
+	if reader.Next() {
+		t.Fatal("Next() = true for a truncated message")
+	}
+	if !errors.Is(reader.Err(), io.ErrUnexpectedEOF) || !errors.Is(reader.Err(), ErrCorruptMessage) {
+		t.Errorf("Err() = %v; want io.ErrUnexpectedEOF and ErrCorruptMessage", reader.Err())
+	}
+}
+
+func TestReaderDamagedLengthDoesNotAllocateIt(t *testing.T) {
+	// A header announcing a message of almost 1 GiB in a file of a few bytes
+	message := []byte("GRIB\x00\x00\x00\x02")
+	message = binary.BigEndian.AppendUint64(message, MAX_MESSAGE_LENGTH)
+	message = append(message, "truncated"...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	reader := NewReader(bytes.NewReader(message))
+	if reader.Next() || !errors.Is(reader.Err(), ErrCorruptMessage) {
+		t.Fatalf("Next() on a truncated message: err = %v; want %v", reader.Err(), ErrCorruptMessage)
+	}
+
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("reading the message allocated %d bytes; want at most what was read plus a chunk", allocated)
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
@@ -0,0 +1,93 @@
+package grib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Selector picks the messages of a field by exact match on their metadata, optional keys match any value.
+// In JSON it is either a shortName like "2t" or an object like
+// {"shortName": "2t", "typeOfLevel": "heightAboveGround", "level": 2, "as": "t2m"}.
+type Selector struct {
+	ShortName   string `json:"shortName"`
+	TypeOfLevel string `json:"typeOfLevel,omitempty"`
+	Level       *int   `json:"level,omitempty"`
+	StepType    string `json:"stepType,omitempty"`
+	// As is the name handlers know the field by, the shortName when empty
+	As string `json:"as,omitempty"`
+	// Optional fields are left out of the result of ExtractGribData when no message matches, instead of failing
+	Optional bool `json:"-"`
+}
+
+func (s *Selector) UnmarshalJSON(data []byte) error {
+	var shortName string
+	if err := json.Unmarshal(data, &shortName); err == nil {
+		*s = Selector{ShortName: shortName}
+		return nil
+	}
+
+	// The alias prevents UnmarshalJSON from calling itself
+	type selector Selector
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var decoded selector
+	if err := decoder.Decode(&decoded); err != nil {
+		return fmt.Errorf("a field is either a shortName or an object with shortName, typeOfLevel, level, stepType and as: %w", err)
+	}
+	*s = Selector(decoded)
+
+	return nil
+}
+
+// Key is the name of the field in the points returned by ExtractGribData
+func (s Selector) Key() string {
+	if s.As != "" {
+		return s.As
+	}
+
+	return s.ShortName
+}
+
+// Matches tells whether a message belongs to the field
+func (s Selector) Matches(metadata Metadata) bool {
+	return metadata.ShortName == s.ShortName &&
+		(s.TypeOfLevel == "" || metadata.TypeOfLevel == s.TypeOfLevel) &&
+		(s.Level == nil || metadata.Level == *s.Level) &&
+		(s.StepType == "" || metadata.StepType == s.StepType)
+}
+
+// Overlaps tells whether a message could match both selectors
+func (s Selector) Overlaps(other Selector) bool {
+	return s.ShortName == other.ShortName &&
+		(s.TypeOfLevel == "" || other.TypeOfLevel == "" || s.TypeOfLevel == other.TypeOfLevel) &&
+		(s.Level == nil || other.Level == nil || *s.Level == *other.Level) &&
+		(s.StepType == "" || other.StepType == "" || s.StepType == other.StepType)
+}
+
+// Equal tells whether both selectors select the same messages under the same key
+func (s Selector) Equal(other Selector) bool {
+	return s.ShortName == other.ShortName && s.TypeOfLevel == other.TypeOfLevel && s.StepType == other.StepType && s.As == other.As &&
+		(s.Level == nil) == (other.Level == nil) && (s.Level == nil || *s.Level == *other.Level)
+}
+
+func (s Selector) String() string {
+	parts := []string{"shortName=" + s.ShortName}
+	if s.TypeOfLevel != "" {
+		parts = append(parts, "typeOfLevel="+s.TypeOfLevel)
+	}
+	if s.Level != nil {
+		parts = append(parts, fmt.Sprintf("level=%d", *s.Level))
+	}
+	if s.StepType != "" {
+		parts = append(parts, "stepType="+s.StepType)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func describeMessage(metadata Metadata) string {
+	return fmt.Sprintf("shortName=%s typeOfLevel=%s level=%d stepType=%s", metadata.ShortName, metadata.TypeOfLevel, metadata.Level, metadata.StepType)
+}
@@ -0,0 +1,29 @@
+package grib
+
+import "testing"
+
+func TestSelectorMatches(t *testing.T) {
+	two, ten := 2, 10
+	temperature := Metadata{ShortName: "2t", TypeOfLevel: "heightAboveGround", Level: 2, StepType: "instant"}
+
+	testCases := []struct {
+		name     string
+		selector Selector
+		expected bool
+	}{
+		{name: "Short name only", selector: Selector{ShortName: "2t"}, expected: true},
+		{name: "All keys", selector: Selector{ShortName: "2t", TypeOfLevel: "heightAboveGround", Level: &two, StepType: "instant"}, expected: true},
+		{name: "Substring of the short name", selector: Selector{ShortName: "t"}, expected: false},
+		{name: "Other level", selector: Selector{ShortName: "2t", Level: &ten}, expected: false},
+		{name: "Other level type", selector: Selector{ShortName: "2t", TypeOfLevel: "surface"}, expected: false},
+		{name: "Other step type", selector: Selector{ShortName: "2t", StepType: "accum"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := tc.selector.Matches(temperature); result != tc.expected {
+				t.Errorf("%s matches %s = %v; want %v", tc.selector, describeMessage(temperature), result, tc.expected)
+			}
+		})
+	}
+}
@@ -8,9 +8,9 @@ import (
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
 )
 
-func Serve() {
+func Serve(config *forecast.Config) {
 	utils.LoadEnv()
-	forecast.Serve()
+	forecast.Serve(config)
 	http.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("ha ha ha ha staying alive"))
 	})
@@ -7,48 +7,60 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
-	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
-	"syscall"
+	"sync"
 
 	"github.com/Michaelvilleneuve/weather-fetch-go/internal/utils"
 )
 
 
-func AnticipateExit() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		utils.Log("Cleaning up before exit...")
-		CleanUpFiles("")
-		os.Exit(0)
-	}()
-}
-
-
 func CleanUpFiles(pattern string) {
-	files, err := os.ReadDir("./tmp")
+	err := filepath.WalkDir("./tmp", func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || (len(pattern) > 0 && !strings.Contains(entry.Name(), pattern)) {
+			return nil
+		}
+		os.Remove(path)
+		return nil
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
+}
 
-	for _, file := range files {
-		if len(pattern) > 0 && !strings.Contains(file.Name(), pattern) {
-			continue
-		}
-		os.Remove("./tmp/" + file.Name())
-	}
+// forecastFile is the path of the file of a forecast for a region and an hour, in tmp or storage
+func forecastFile(dir string, region string, commonName string, hour string) string {
+	return filepath.Join(dir, region, fmt.Sprintf("%s_%s.json.gz", commonName, hour))
 }
 
+// ForecastFile is the path of the published file of a forecast for a region and an hour
+func ForecastFile(region string, commonName string, hour string) string {
+	return forecastFile("storage", region, commonName, hour)
+}
+
+// Save writes the rows of a forecast to tmp until the hour is published, NaN values are points without data and written as null
+func Save(data [][]float64, region string, packageName string, hour string, original_time string, units string) (string, error) {
+	rows := make([][]*float64, len(data))
+	for i, row := range data {
+		rows[i] = make([]*float64, len(row))
+		for j := range row {
+			if !math.IsNaN(row[j]) {
+				rows[i][j] = &row[j]
+			}
+		}
+	}
 
-func Save(data [][]float64, packageName string, hour string, original_time string) (string, error) {
 	payload := map[string]interface{}{
-		"data": data,
+		"data": rows,
 		"hour": hour,
 		"original_time": original_time,
+		"units": units,
 	}
 
 	jsonPayload, err := json.Marshal(payload)
@@ -61,15 +73,29 @@ func Save(data [][]float64, packageName string, hour string, original_time strin
 	gz.Write(jsonPayload)
 	gz.Close()
 
-	os.WriteFile(fmt.Sprintf("tmp/%s_%s.json.gz", packageName, hour), buf.Bytes(), 0644)
+	file := forecastFile("tmp", region, packageName, hour)
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	os.WriteFile(file, buf.Bytes(), 0644)
 
 	return "", nil
 }
 
-func IsUpToDate(packageName string, dt string) bool {
+// IsUpToDate tells whether dt is the run already in storage. A region without a storage folder, added to
+// the configuration or left empty by an older storage layout, has nothing to serve so the run is processed again.
+func IsUpToDate(packageName string, dt string, regions []string) bool {
 	lastDownloaded, err := os.ReadFile(fmt.Sprintf("storage/%s_current_run_datetime.txt", packageName))
 	isUpToDate := bytes.Equal(lastDownloaded, []byte(dt))
 
+	for _, region := range regions {
+		if _, statErr := os.Stat(filepath.Join("storage", region)); statErr != nil {
+			isUpToDate = false
+		}
+	}
+
 	if err != nil || !isUpToDate {
 		os.WriteFile(fmt.Sprintf("tmp/%s_current_run_datetime.txt", packageName), []byte(dt), 0644)
 		return false
@@ -78,34 +104,208 @@ func IsUpToDate(packageName string, dt string) bool {
 	return isUpToDate
 }
 
-func RollOut(packageName string, commonNames []string) {
+// PublishHour moves the files of a single hour to storage so that they are served right away,
+// and records which run the hour of each param now comes from. The run is only recorded for a param
+// when its files of every region were moved, otherwise storage still holds the previous ones.
+func PublishHour(regions []string, commonNames []string, run string, hour string) {
+	published := make(map[string]bool, len(commonNames))
 	for _, commonName := range commonNames {
-		files, err := filepath.Glob(fmt.Sprintf("tmp/%s_*.json.gz", commonName))
+		published[commonName] = true
+	}
+
+	for _, region := range regions {
+		err := os.MkdirAll(filepath.Join("storage", region), 0755)
 		if err != nil {
-			utils.Log("Error during globbing: " + err.Error())
-			return
+			utils.Log("Error creating storage of region " + region + ": " + err.Error())
+			clear(published)
+			continue
 		}
 
-		for _, src := range files {
-			dst := filepath.Join("storage", filepath.Base(src))
+		for _, commonName := range commonNames {
+			src := forecastFile("tmp", region, commonName, hour)
+			dst := forecastFile("storage", region, commonName, hour)
 			err := moveFile(src, dst)
 			if err != nil {
 				utils.Log("Error moving file " + src + ": " + err.Error())
+				published[commonName] = false
 			}
 		}
+	}
 
+	for _, commonName := range commonNames {
+		if !published[commonName] {
+			continue
+		}
+
+		err := recordHourRun(commonName, hour, run)
+		if err != nil {
+			utils.Log("Error recording run of hour " + hour + " for " + commonName + ": " + err.Error())
+		}
+	}
+}
+
+// RollOut publishes every hour of a run at once, then marks the run as the current one.
+// skipped lists by hour the params the run has no data for, they are withdrawn instead.
+func RollOut(packageName string, regions []string, commonNames []string, run string, hours []string, skipped map[string][]string) {
+	for _, hour := range hours {
+		published := slices.DeleteFunc(slices.Clone(commonNames), func(commonName string) bool {
+			return slices.Contains(skipped[hour], commonName)
+		})
+		PublishHour(regions, published, run, hour)
+		WithdrawHour(regions, skipped[hour], hour)
 	}
 
-	
+	FinishRollOut(packageName, run)
+}
+
+// FinishRollOut marks the run as the current one once all its hours have been published
+func FinishRollOut(packageName string, run string) {
 	// Move the current_run_datetime.txt file
 	err := moveFile(fmt.Sprintf("tmp/%s_current_run_datetime.txt", packageName), fmt.Sprintf("storage/%s_current_run_datetime.txt", packageName))
 	if err != nil {
 		utils.Log("Error moving file " + fmt.Sprintf("tmp/%s_current_run_datetime.txt", packageName) + ": " + err.Error())
 	}
 
+	err = keepGribFiles(packageName, run)
+	if err != nil {
+		utils.Log("Error keeping GRIB files of " + packageName + " " + run + ": " + err.Error())
+	}
+
 	CleanUpFiles(packageName)
 }
 
+// GribFile is where the GRIB file of an hour is kept once its run is rolled out, for /admin/inventory
+func GribFile(packageName string, run string, hour string) string {
+	return filepath.Join("storage", "grib", fmt.Sprintf("file_%s_%s_%s.grib2", packageName, run, hour))
+}
+
+// keepGribFiles moves the GRIB files of a run out of tmp in place of those of the previous run of the package
+func keepGribFiles(packageName string, run string) error {
+	files, err := filepath.Glob(fmt.Sprintf("tmp/file_%s_%s_*.grib2", packageName, run))
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Join("storage", "grib"), 0755)
+	if err != nil {
+		return err
+	}
+
+	previous, _ := filepath.Glob(GribFile(packageName, "*", "*"))
+	for _, file := range previous {
+		os.Remove(file)
+	}
+
+	for _, file := range files {
+		err := moveFile(file, filepath.Join("storage", "grib", filepath.Base(file)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithdrawHour removes the published files of an hour of some params, so that the files of a previous
+// run aren't served as current when the current run has no data for them
+func WithdrawHour(regions []string, commonNames []string, hour string) {
+	for _, region := range regions {
+		for _, commonName := range commonNames {
+			file := forecastFile("storage", region, commonName, hour)
+			err := os.Remove(file)
+			if err != nil && !os.IsNotExist(err) {
+				utils.Log("Error removing file " + file + ": " + err.Error())
+			}
+		}
+	}
+
+	for _, commonName := range commonNames {
+		err := forgetHourRun(commonName, hour)
+		if err != nil {
+			utils.Log("Error forgetting run of hour " + hour + " for " + commonName + ": " + err.Error())
+		}
+	}
+}
+
+var hoursMutex sync.Mutex
+
+// hoursFile records the run every published hour of a param comes from, hours of a param
+// can come from different runs while a run is rolled out progressively
+func hoursFile(commonName string) string {
+	return fmt.Sprintf("storage/%s_hours.json", commonName)
+}
+
+// HourRuns returns the run each published hour of a param comes from
+func HourRuns(commonName string) (map[string]string, error) {
+	hoursMutex.Lock()
+	defer hoursMutex.Unlock()
+
+	return readHourRuns(commonName)
+}
+
+func readHourRuns(commonName string) (map[string]string, error) {
+	hourRuns := make(map[string]string)
+
+	content, err := os.ReadFile(hoursFile(commonName))
+	if os.IsNotExist(err) {
+		return hourRuns, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, &hourRuns)
+	return hourRuns, err
+}
+
+func recordHourRun(commonName string, hour string, run string) error {
+	hoursMutex.Lock()
+	defer hoursMutex.Unlock()
+
+	hourRuns, err := readHourRuns(commonName)
+	if err != nil {
+		return err
+	}
+	hourRuns[hour] = run
+
+	return writeHourRuns(commonName, hourRuns)
+}
+
+func forgetHourRun(commonName string, hour string) error {
+	hoursMutex.Lock()
+	defer hoursMutex.Unlock()
+
+	hourRuns, err := readHourRuns(commonName)
+	if err != nil {
+		return err
+	}
+	if _, exists := hourRuns[hour]; !exists {
+		return nil
+	}
+	delete(hourRuns, hour)
+
+	return writeHourRuns(commonName, hourRuns)
+}
+
+// writeHourRuns replaces the hours file of a param, callers must hold hoursMutex
+func writeHourRuns(commonName string, hourRuns map[string]string) error {
+	content, err := json.Marshal(hourRuns)
+	if err != nil {
+		return err
+	}
+
+	// Write next to the destination then rename so that readers never see a partial file
+	tmpFile := hoursFile(commonName) + ".tmp"
+	err = os.WriteFile(tmpFile, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, hoursFile(commonName))
+}
+
+// moveFile renames src to dst, when they are on different devices the file is copied
+// next to dst first so that dst is replaced atomically.
 func moveFile(src, dst string) error {
 	err := os.Rename(src, dst)
 	if err == nil {
@@ -118,7 +318,8 @@ func moveFile(src, dst string) error {
 	}
 	defer srcFile.Close()
 
-	dstFile, err := os.Create(dst)
+	tmpDst := dst + ".tmp"
+	dstFile, err := os.Create(tmpDst)
 	if err != nil {
 		return err
 	}
@@ -134,5 +335,10 @@ func moveFile(src, dst string) error {
 		return err
 	}
 
+	err = os.Rename(tmpDst, dst)
+	if err != nil {
+		return err
+	}
+
 	return os.Remove(src)
 }
\ No newline at end of file
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"compress/gzip"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPublishHourRecordsRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"tmp", "storage"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, hour := range []string{"01", "02"} {
+		Save([][]float64{{0.5, 39.5, 18}}, "valencia", "temperature", hour, "2025-06-19T03:00:00Z", "K")
+	}
+	RollOut("SP1", []string{"valencia"}, []string{"temperature", "humidity"}, "2025-06-19T03:00:00Z", []string{"01", "02"}, nil)
+
+	// Hour 02 of the new run was not saved, so storage still holds the one of the previous run
+	Save([][]float64{{0.5, 39.5, 20}}, "valencia", "temperature", "01", "2025-06-19T06:00:00Z", "K")
+	PublishHour([]string{"valencia"}, []string{"temperature"}, "2025-06-19T06:00:00Z", "01")
+	PublishHour([]string{"valencia"}, []string{"temperature"}, "2025-06-19T06:00:00Z", "02")
+
+	if _, err := os.Stat("storage/valencia/temperature_01.json.gz"); err != nil {
+		t.Errorf("hour 01 was not published: %v", err)
+	}
+
+	hourRuns, err := HourRuns("temperature")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"01": "2025-06-19T06:00:00Z", "02": "2025-06-19T03:00:00Z"}
+	for hour, run := range expected {
+		if hourRuns[hour] != run {
+			t.Errorf("run of hour %s = %q; want %q", hour, hourRuns[hour], run)
+		}
+	}
+
+	// Nothing was saved for humidity so none of its hours was published
+	if hourRuns, err := HourRuns("humidity"); err != nil || len(hourRuns) != 0 {
+		t.Errorf("HourRuns(humidity) = %v, %v; want no hour", hourRuns, err)
+	}
+}
+
+func TestSaveWritesMissingValuesAsNull(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if _, err := Save([][]float64{{0.5, 39.5, 20}, {0.5, 39.6, math.NaN()}}, "valencia", "temperature", "01", "2025-06-19T06:00:00Z", "K"); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open("tmp/valencia/temperature_01.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), `"data":[[0.5,39.5,20],[0.5,39.6,null]]`) {
+		t.Errorf("saved %s; want the missing value as null", content)
+	}
+}
+
+func TestIsUpToDateWithoutRegionStorage(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"tmp", "storage"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Storage of an older layout, with the files of every param right in storage/
+	if err := os.WriteFile("storage/SP1_current_run_datetime.txt", []byte("2025-06-19T03:00:00Z"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if IsUpToDate("SP1", "2025-06-19T03:00:00Z", []string{"valencia"}) {
+		t.Error("run is up to date although nothing was published for valencia")
+	}
+
+	if err := os.Mkdir("storage/valencia", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !IsUpToDate("SP1", "2025-06-19T03:00:00Z", []string{"valencia"}) {
+		t.Error("run is not up to date once valencia is published")
+	}
+}
+
+func TestFinishRollOutKeepsGribFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"tmp", "storage/grib"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, file := range []string{
+		"storage/grib/file_SP1_2025-06-19T03:00:00Z_01.grib2",
+		"storage/grib/file_SP2_2025-06-19T03:00:00Z_01.grib2",
+		"tmp/file_SP1_2025-06-19T06:00:00Z_01.grib2",
+		"tmp/file_SP1_2025-06-19T06:00:00Z_02.grib2",
+		"tmp/file_SP1_2025-06-19T06:00:00Z_03.grib2.part",
+	} {
+		if err := os.WriteFile(file, []byte("GRIB"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	FinishRollOut("SP1", "2025-06-19T06:00:00Z")
+
+	expected := map[string]bool{
+		GribFile("SP1", "2025-06-19T03:00:00Z", "01"):     false,
+		GribFile("SP1", "2025-06-19T06:00:00Z", "01"):     true,
+		GribFile("SP1", "2025-06-19T06:00:00Z", "02"):     true,
+		GribFile("SP2", "2025-06-19T03:00:00Z", "01"):     true,
+		"tmp/file_SP1_2025-06-19T06:00:00Z_01.grib2":      false,
+		"tmp/file_SP1_2025-06-19T06:00:00Z_03.grib2.part": false,
+	}
+	for file, exists := range expected {
+		if _, err := os.Stat(file); (err == nil) != exists {
+			t.Errorf("%s exists = %v; want %v", file, err == nil, exists)
+		}
+	}
+}
+
+func TestRollOutWithdrawsSkippedParams(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"tmp", "storage"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, commonName := range []string{"temperature", "wind_gust"} {
+		Save([][]float64{{0.5, 39.5, 18}}, "valencia", commonName, "01", "2025-06-19T03:00:00Z", "")
+	}
+	RollOut("SP1", []string{"valencia"}, []string{"temperature", "wind_gust"}, "2025-06-19T03:00:00Z", []string{"01"}, nil)
+
+	// The next run has no gusts, the ones of the previous run must not be served anymore
+	Save([][]float64{{0.5, 39.5, 20}}, "valencia", "temperature", "01", "2025-06-19T06:00:00Z", "K")
+	RollOut("SP1", []string{"valencia"}, []string{"temperature", "wind_gust"}, "2025-06-19T06:00:00Z", []string{"01"}, map[string][]string{"01": {"wind_gust"}})
+
+	if _, err := os.Stat(ForecastFile("valencia", "wind_gust", "01")); !os.IsNotExist(err) {
+		t.Errorf("gusts of the previous run are still published")
+	}
+	if hourRuns, err := HourRuns("wind_gust"); err != nil || len(hourRuns) != 0 {
+		t.Errorf("HourRuns(wind_gust) = %v, %v; want no hour", hourRuns, err)
+	}
+	if hourRuns, err := HourRuns("temperature"); err != nil || hourRuns["01"] != "2025-06-19T06:00:00Z" {
+		t.Errorf("HourRuns(temperature) = %v, %v; want hour 01 from the 06:00 run", hourRuns, err)
+	}
+}
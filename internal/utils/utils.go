@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -19,4 +21,34 @@ func Log(message string) {
 	if os.Getenv("DEBUG") == "true" {
 		fmt.Println(message)
 	}
-}
\ No newline at end of file
+}
+
+// GetEnvInt returns the integer value of an environment variable, or defaultValue when it is unset or invalid
+func GetEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetEnvFloat returns the decimal value of an environment variable like "0.5", or defaultValue when it is unset or invalid
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetEnvDuration returns the value of an environment variable like "90s" or "5m", or defaultValue when it is unset or invalid
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}